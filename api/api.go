@@ -3,6 +3,8 @@ package api
 import (
 	"github.com/gofiber/fiber/v2"
 
+	"app/api/auth"
+	"app/api/routes/auditRoutes"
 	"app/api/routes/userRoutes"
 )
 
@@ -13,8 +15,11 @@ func TestHandler(c *fiber.Ctx) error {
 func SetRoutes(app *fiber.App) {
 	api := app.Group("/")
 	userRoutes.SetUserRoutes(api)
+	auditRoutes.SetAuditRoutes(api)
 
 	api.Get("/", TestHandler)
+	// Public Keys For Services Verifying Our Tokens
+	api.Get("/.well-known/jwks.json", auth.JWKS)
 }
 
 func SetupAPI(app *fiber.App) {
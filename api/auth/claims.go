@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"app/config"
+)
+
+// Every Token We Issue - sub Is The User ID
+type Claims struct {
+	jwt.RegisteredClaims
+	Role   string   `json:"role,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+	// Every Role Active When The Token Was Issued - Role Is Always First
+	Roles []string `json:"roles,omitempty"`
+	// Logout Everywhere Bumps The User's Generation, Older Tokens Are Rejected
+	Generation int64 `json:"gen"`
+	// The Address An Email Verification Token Confirms
+	Email string `json:"email,omitempty"`
+}
+
+// Tokens For Anything But The API Get Their Own Audience So They Can't Be Used As Access Tokens
+const (
+	AudienceEmailVerification = "email-verification"
+	AudienceMFAChallenge      = "mfa-challenge"
+	AudienceAuditCheckpoint   = "audit-checkpoint"
+)
+
+/*
+Validation Failures - The Message Is Sent Back As The
+error_description Of The WWW-Authenticate Header
+*/
+var (
+	ErrTokenMissing     = errors.New("Missing Bearer Token")
+	ErrTokenMalformed   = errors.New("The Access Token Is Malformed")
+	ErrTokenSignature   = errors.New("The Access Token Signature Is Invalid")
+	ErrTokenExpired     = errors.New("The Access Token Expired")
+	ErrTokenNotYetValid = errors.New("The Access Token Is Not Valid Yet")
+	ErrTokenIssuer      = errors.New("The Access Token Has The Wrong Issuer")
+	ErrTokenAudience    = errors.New("The Access Token Has The Wrong Audience")
+	ErrTokenClaims      = errors.New("The Access Token Is Missing Required Claims")
+	ErrTokenRevoked     = errors.New("The Access Token Was Revoked")
+	ErrTokenUnknown     = errors.New("The Access Token Is Not Recognised")
+)
+
+func (claims *Claims) UserID() (uint, error) {
+	id, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return 0, ErrTokenClaims
+	}
+	return uint(id), nil
+}
+
+// Checks Times, Issuer And Audience - All Times Allow auth.leeway Of Clock Skew
+func (claims *Claims) Validate(now time.Time, auth config.AuthConfig, audience string) error {
+	leeway := time.Duration(auth.Leeway) * time.Second
+
+	if claims.ExpiresAt == nil || claims.Subject == "" || claims.ID == "" {
+		return ErrTokenClaims
+	}
+	if !now.Before(claims.ExpiresAt.Add(leeway)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != nil && now.Add(leeway).Before(claims.NotBefore.Time) {
+		return ErrTokenNotYetValid
+	}
+	if claims.IssuedAt != nil && now.Add(leeway).Before(claims.IssuedAt.Time) {
+		return ErrTokenNotYetValid
+	}
+	if claims.Issuer != auth.Issuer {
+		return ErrTokenIssuer
+	}
+	if !claims.VerifyAudience(audience, true) {
+		return ErrTokenAudience
+	}
+	return nil
+}
+
+// Verifies The Signature Then The Claims For The Given Audience
+func ParseToken(raw string, audience string) (*Claims, error) {
+	keys, err := Keys()
+	if err != nil {
+		return nil, err
+	}
+	claims := new(Claims)
+
+	_, err = jwt.ParseWithClaims(raw, claims, keys.Keyfunc,
+		jwt.WithValidMethods(keys.Methods()),
+		// Claims Are Checked Below With Leeway And Precise Errors
+		jwt.WithoutClaimsValidation(),
+	)
+	if err != nil {
+		var validationErr *jwt.ValidationError
+		if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorMalformed != 0 {
+			return nil, ErrTokenMalformed
+		}
+		return nil, ErrTokenSignature
+	}
+
+	err = claims.Validate(time.Now(), config.Get().Auth, audience)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Fills In The Registered Claims For A Token Lasting ttl
+func NewClaims(userID uint, audience string, ttl time.Duration) (*Claims, error) {
+	jti, err := RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    config.Get().Auth.Issuer,
+			Subject:   fmt.Sprintf("%d", userID),
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        jti,
+		},
+	}, nil
+}
@@ -0,0 +1,121 @@
+package auth_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"app/api/auth"
+	"app/config"
+	"app/testutil"
+)
+
+func TestClaimsValidate(t *testing.T) {
+	cfg := config.Default().Auth
+	now := time.Now()
+	leeway := time.Duration(cfg.Leeway) * time.Second
+
+	valid := func() *auth.Claims {
+		return &auth.Claims{RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    cfg.Issuer,
+			Subject:   "1",
+			Audience:  jwt.ClaimStrings{cfg.Audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        "jti",
+		}}
+	}
+
+	cases := []struct {
+		name     string
+		modify   func(c *auth.Claims)
+		expected error
+	}{
+		{"Valid", func(c *auth.Claims) {}, nil},
+		{"Expired", func(c *auth.Claims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-leeway - time.Second)) }, auth.ErrTokenExpired},
+		{"Expired Within Leeway", func(c *auth.Claims) { c.ExpiresAt = jwt.NewNumericDate(now.Add(-leeway / 2)) }, nil},
+		{"Not Before", func(c *auth.Claims) { c.NotBefore = jwt.NewNumericDate(now.Add(leeway + time.Minute)) }, auth.ErrTokenNotYetValid},
+		{"Issued In The Future", func(c *auth.Claims) { c.IssuedAt = jwt.NewNumericDate(now.Add(leeway + time.Minute)) }, auth.ErrTokenNotYetValid},
+		{"Wrong Issuer", func(c *auth.Claims) { c.Issuer = "someone-else" }, auth.ErrTokenIssuer},
+		{"Wrong Audience", func(c *auth.Claims) { c.Audience = jwt.ClaimStrings{"another-api"} }, auth.ErrTokenAudience},
+		{"Missing Audience", func(c *auth.Claims) { c.Audience = nil }, auth.ErrTokenAudience},
+		{"Missing Expiry", func(c *auth.Claims) { c.ExpiresAt = nil }, auth.ErrTokenClaims},
+		{"Missing Subject", func(c *auth.Claims) { c.Subject = "" }, auth.ErrTokenClaims},
+		{"Missing jti", func(c *auth.Claims) { c.ID = "" }, auth.ErrTokenClaims},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := valid()
+			tc.modify(claims)
+			err := claims.Validate(now, cfg, cfg.Audience)
+			if err != tc.expected {
+				t.Fatalf("\nUnexpected Error: %v Expected: %v\n", err, tc.expected)
+			}
+		})
+	}
+}
+
+// Rejections Carry An RFC 6750 Challenge Naming The Reason
+func TestValidateJWTChallenge(t *testing.T) {
+	app := testutil.Setup(t)
+	u := testutil.CreateUser(t, "tester", "123", "default")
+	cfg := config.Get().Auth
+
+	sign := func(modify func(c *auth.Claims)) string {
+		claims, err := auth.NewClaims(u.ID, cfg.Audience, time.Minute)
+		if err != nil {
+			t.Fatalf("\nFailed To Build Claims: %s\n", err.Error())
+		}
+		modify(claims)
+		keys, err := auth.Keys()
+		if err != nil {
+			t.Fatalf("\nNo Keys: %s\n", err.Error())
+		}
+		token, err := keys.Sign(claims)
+		if err != nil {
+			t.Fatalf("\nFailed To Sign: %s\n", err.Error())
+		}
+		return "Bearer " + token
+	}
+
+	cases := []struct {
+		name          string
+		authorization string
+		expected      error
+	}{
+		{"Missing", "", auth.ErrTokenMissing},
+		{"Malformed", "Bearer not-a-token", auth.ErrTokenMalformed},
+		{"Expired", sign(func(c *auth.Claims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) }), auth.ErrTokenExpired},
+		{"Wrong Audience", sign(func(c *auth.Claims) { c.Audience = jwt.ClaimStrings{"mfa-challenge"} }), auth.ErrTokenAudience},
+		{"Wrong Issuer", sign(func(c *auth.Claims) { c.Issuer = "someone-else" }), auth.ErrTokenIssuer},
+		{"Bad Signature", sign(func(c *auth.Claims) {}) + "x", auth.ErrTokenSignature},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, body := testutil.Request(t, app, http.MethodGet, "/user/", nil, tc.authorization)
+			testutil.ExpectStatus(t, res, body, 401)
+
+			challenge := res.Header.Get("WWW-Authenticate")
+			if !strings.HasPrefix(challenge, "Bearer ") {
+				t.Fatalf("\nMissing Bearer Challenge: %q\n", challenge)
+			}
+			if tc.expected == auth.ErrTokenMissing {
+				if strings.Contains(challenge, "error=") {
+					t.Fatalf("\nMissing Tokens Shouldn't Carry An Error Code: %q\n", challenge)
+				}
+				return
+			}
+			if !strings.Contains(challenge, `error="invalid_token"`) || !strings.Contains(challenge, tc.expected.Error()) {
+				t.Fatalf("\nUnexpected Challenge: %q Expected: %s\n", challenge, tc.expected.Error())
+			}
+		})
+	}
+
+	// And The Real Thing Works
+	res, body := testutil.Request(t, app, http.MethodGet, "/user/", nil, sign(func(c *auth.Claims) {}))
+	testutil.ExpectStatus(t, res, body, 200)
+}
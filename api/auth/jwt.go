@@ -1,92 +1,168 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"app/config"
+	"app/metrics"
+	"app/tracing"
 )
 
-var JWTSecretKey = []byte(config.JWT_SECRET)
+// Short Lived Access Token For The API Audience - roles Lists userRole First
+func IssueJWT(ctx context.Context, userId uint, userRole string, roles []string) (string, error) {
+	cfg := config.Get().Auth
 
-func IssueJWT(userId uint, userRole string) (string, error) {
+	claims, err := NewClaims(userId, cfg.Audience, time.Duration(cfg.JWTExpires)*time.Second)
+	if err != nil {
+		return "", err
+	}
+	claims.Role = userRole
+	claims.Roles = roles
 
-	claims := jwt.MapClaims{
-		"user_id": fmt.Sprintf("%d", userId),
-		"role":    userRole,
-		"exp":     time.Now().Add(time.Minute * 3600).Unix(), // 1 Day
+	// Read Fresh So A Logout Everywhere On Another Instance Applies Straight Away
+	claims.Generation, err = CurrentGeneration(ctx, userId)
+	if err != nil {
+		return "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS384, claims)
+	keys, err := Keys()
+	if err != nil {
+		return "", err
+	}
+	token, err := keys.Sign(claims)
+	if err != nil {
+		return "", err
+	}
+	metrics.TokensIssued.WithLabelValues(metrics.TokenAccess).Inc()
+	return token, nil
+}
 
-	t, err := token.SignedString(JWTSecretKey)
+func ValidateJWT(c *fiber.Ctx) error {
+	ctx, span := tracing.Start(c.UserContext(), "auth.ValidateJWT")
+	claims, err := checkJWT(ctx, c)
+	switch tokenValidated(span, metrics.TokenAccess, err) {
+	case resultValid:
+		span.End()
+	case resultError:
+		tracing.End(span, err)
+		return c.SendStatus(500)
+	default:
+		span.End()
+		return Unauthorized(c, err)
+	}
 
-	return t, err
+	// Add Values To Locals
+	c.Locals("user_id", claims.Subject)
+	c.Locals("role", claims.Role)
+	c.Locals("roles", claims.Roles)
+	c.Locals("scopes", claims.Scopes)
+	c.Locals("jti", claims.ID)
+	c.Locals("token_exp", claims.ExpiresAt.Time)
+	return c.Next()
 }
 
-func ValidateJWT(c *fiber.Ctx) error {
-	headers := c.GetReqHeaders()
-	authHeader := headers["Authorization"]
-	authToken := strings.Split(authHeader, " ")
+// The Bearer Token's Claims Once It's Verified And Not Revoked
+func checkJWT(ctx context.Context, c *fiber.Ctx) (*Claims, error) {
+	raw, ok := bearerToken(c)
+	if !ok {
+		return nil, ErrTokenMissing
+	}
 
-	if len(authToken) != 2 {
-		return c.SendStatus(401)
+	claims, err := ParseToken(raw, config.Get().Auth.Audience)
+	if err != nil {
+		return nil, err
 	}
 
-	token, err := jwt.Parse(authToken[1], func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("There was an error")
-		}
-		return JWTSecretKey, nil
-	})
+	user_id, err := claims.UserID()
+	if err != nil {
+		return nil, err
+	}
 
+	// Check Logout
+	revoked, err := IsRevoked(ctx, claims.ID, user_id, claims.Generation)
 	if err != nil {
-		return c.SendStatus(401)
-	}
-
-	if token.Valid {
-		claims, ok := token.Claims.(jwt.MapClaims)
-		// fmt.Printf("Claims:\nRole:%s\nUserId:%s\nExp: %f\n", claims["role"], claims["user_id"], claims["exp"])
-		if ok {
-			// Check Expiration
-			err = TokenExpired(claims)
-			if err != nil {
-				return c.SendStatus(401)
-			}
-			// Add Values To Locals
-			c.Locals("user_id", fmt.Sprintf("%s", claims["user_id"]))
-			c.Locals("role", fmt.Sprintf("%s", claims["role"]))
-		}
-		return c.Next()
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
 	}
+	return claims, nil
+}
 
-	return c.SendStatus(500)
+/*
+401 With An RFC 6750 Challenge - A Missing Token Gets No Error Code
+So Clients Know To Authenticate Rather Than Refresh
+*/
+func Unauthorized(c *fiber.Ctx, err error) error {
+	challenge := `Bearer realm="api"`
+	if !errors.Is(err, ErrTokenMissing) {
+		challenge += fmt.Sprintf(`, error="invalid_token", error_description="%s"`, err.Error())
+	}
+	c.Set(fiber.HeaderWWWAuthenticate, challenge)
+	return c.Status(401).JSON(fiber.Map{"error": err.Error()})
 }
 
-// Use Validate JWT First
-func ValidateAdmin(c *fiber.Ctx) error {
-	if fmt.Sprintf("%s", c.Locals("role")) != "admin" {
-		return c.SendStatus(403)
+const (
+	resultValid = "valid"
+	// Checking Failed Rather Than The Token
+	resultError = "error"
+)
+
+/*
+Counts A Presented Token Under Why It Was Refused, Or valid, And Notes
+The Same On The Span Checking It
+*/
+func tokenValidated(span trace.Span, kind string, err error) string {
+	result := validationResult(err)
+	metrics.TokenValidations.WithLabelValues(kind, result).Inc()
+	if span != nil {
+		span.SetAttributes(attribute.String("token.type", kind), attribute.String("token.result", result))
 	}
-	return c.Next()
+	return result
 }
 
-func TokenExpired(claims jwt.MapClaims) error {
-	tokenExp, err := strconv.ParseFloat(fmt.Sprintf("%f", claims["exp"]), 64)
-	if err != nil {
-		return fmt.Errorf("Invalid Token")
+func validationResult(err error) string {
+	if err == nil {
+		return resultValid
 	}
-	now := time.Now().Add(time.Minute * 3600).Unix()
-	diff := now - int64(tokenExp)
-	b := diff > config.JWT_EXPIRES
-	// Token Is Expired
-	if b {
-		return fmt.Errorf("Token Is Expired")
+	results := []struct {
+		err    error
+		result string
+	}{
+		{ErrTokenMissing, "missing"},
+		{ErrTokenMalformed, "malformed"},
+		{ErrTokenSignature, "signature"},
+		{ErrTokenExpired, "expired"},
+		{ErrTokenNotYetValid, "not_yet_valid"},
+		{ErrTokenIssuer, "issuer"},
+		{ErrTokenAudience, "audience"},
+		{ErrTokenClaims, "claims"},
+		{ErrTokenRevoked, "revoked"},
+		{ErrTokenUnknown, "unknown"},
+		{ErrRefreshTokenInvalid, "invalid"},
+		{ErrRefreshTokenReused, "reused"},
+	}
+	for _, r := range results {
+		if errors.Is(err, r.err) {
+			return r.result
+		}
+	}
+	return resultError
+}
+
+// Token From An "Authorization: Bearer <token>" Header - Older Clients Send "Bearer: <token>"
+func bearerToken(c *fiber.Ctx) (string, bool) {
+	scheme, token, found := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if !found || !strings.EqualFold(strings.TrimSuffix(scheme, ":"), "Bearer") || token == "" {
+		return "", false
 	}
-	return nil
+	return strings.TrimSpace(token), true
 }
@@ -0,0 +1,333 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+
+	"app/config"
+)
+
+/*
+A Key Tokens Can Be Verified With - Signing Keys Also Hold The Private Half
+Asymmetric Keys Are Identified By Their RFC 7638 JWK Thumbprint (kid)
+*/
+type Key struct {
+	ID        string
+	Algorithm string
+	Method    jwt.SigningMethod
+	Private   interface{} // []byte For HMAC, crypto.Signer Otherwise
+	Public    interface{} // []byte For HMAC, crypto.PublicKey Otherwise
+}
+
+/*
+Tokens Are Signed With Signing And Verified With Any Key In The Ring
+To Rotate: Add The New Key As A Verification Key, Deploy, Swap It To
+The Signing Key With The Old One As A Verification Key, Deploy, Then
+Drop The Old Key Once Its Tokens Have Expired
+*/
+type KeyRing struct {
+	Signing *Key
+	keys    map[string]*Key
+	methods []string
+}
+
+var keyRing atomic.Pointer[KeyRing]
+
+// Tokens Can't Be Signed Or Checked - A Server Fault, Not The Caller's
+var ErrKeysUnavailable = errors.New("Signing Keys Unavailable")
+
+// Builds The Key Ring From Config And Makes It Current
+func LoadKeys(cfg config.AuthConfig) error {
+	ring, err := NewKeyRing(cfg)
+	if err != nil {
+		return err
+	}
+	keyRing.Store(ring)
+	return nil
+}
+
+/*
+Current Key Ring - Built From The Loaded Config If LoadKeys Hasn't Run.
+Errors Wrap ErrKeysUnavailable
+*/
+func Keys() (*KeyRing, error) {
+	ring := keyRing.Load()
+	if ring == nil {
+		var err error
+		ring, err = NewKeyRing(config.Get().Auth)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrKeysUnavailable, err.Error())
+		}
+		keyRing.Store(ring)
+	}
+	return ring, nil
+}
+
+func NewKeyRing(cfg config.AuthConfig) (*KeyRing, error) {
+	method := jwt.GetSigningMethod(cfg.SigningAlg)
+	if method == nil {
+		return nil, fmt.Errorf("Unsupported Signing Algorithm: %s", cfg.SigningAlg)
+	}
+
+	ring := &KeyRing{keys: map[string]*Key{}}
+
+	// Shared Secret - Nothing To Publish
+	if _, ok := method.(*jwt.SigningMethodHMAC); ok {
+		secret := []byte(cfg.JWTSecret)
+		ring.Signing = &Key{Algorithm: method.Alg(), Method: method, Private: secret, Public: secret}
+		ring.add(ring.Signing)
+		return ring, nil
+	}
+
+	signing, err := loadKeyFile(cfg.SigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if signing.Private == nil {
+		return nil, fmt.Errorf("%s: Signing Key Must Be A Private Key", cfg.SigningKeyFile)
+	}
+	if signing.Algorithm != method.Alg() {
+		return nil, fmt.Errorf("%s: Key Is For %s Not %s", cfg.SigningKeyFile, signing.Algorithm, method.Alg())
+	}
+	ring.Signing = signing
+	ring.add(signing)
+
+	for _, path := range cfg.VerificationKeyFiles {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		ring.add(key)
+	}
+	return ring, nil
+}
+
+func (ring *KeyRing) add(key *Key) {
+	if _, exists := ring.keys[key.ID]; exists {
+		return
+	}
+	ring.keys[key.ID] = key
+	for _, alg := range ring.methods {
+		if alg == key.Algorithm {
+			return
+		}
+	}
+	ring.methods = append(ring.methods, key.Algorithm)
+}
+
+// Algorithms Present In The Ring - Anything Else Is Rejected Before Verifying
+func (ring *KeyRing) Methods() []string {
+	return ring.methods
+}
+
+// Signs Claims With The Signing Key And Stamps Its kid
+func (ring *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ring.Signing.Method, claims)
+	if ring.Signing.ID != "" {
+		token.Header["kid"] = ring.Signing.ID
+	}
+	return token.SignedString(ring.Signing.Private)
+}
+
+// jwt.Keyfunc - Picks The Key By kid And Refuses Algorithm Mismatches
+func (ring *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := ring.keys[kid]
+	// Tokens Signed Before kid Was Added
+	if !ok && kid == "" {
+		key, ok = ring.Signing, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("Unknown Signing Key: %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("Algorithm %s Doesn't Match Key %q", token.Method.Alg(), kid)
+	}
+	return key.Public, nil
+}
+
+/*
+	JWKS
+*/
+
+type JWK struct {
+	KTY string `json:"kty"`
+	KID string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC And OKP
+	CRV string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Public Halves Of Every Asymmetric Key - Empty When Using A Shared Secret
+func (ring *KeyRing) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range ring.keys {
+		jwk, err := publicJWK(key.Public)
+		if err != nil {
+			continue
+		}
+		jwk.KID = key.ID
+		jwk.Use = "sig"
+		jwk.Alg = key.Algorithm
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// GET /.well-known/jwks.json
+func JWKS(c *fiber.Ctx) error {
+	keys, err := Keys()
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(200).JSON(keys.JWKS())
+}
+
+/*
+	Loading
+*/
+
+func loadKeyFile(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable To Read Key File: %w", err)
+	}
+	key, err := parsePEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// Accepts PKCS#8, PKCS#1 And SEC 1 Private Keys Or PKIX And PKCS#1 Public Keys
+func parsePEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("No PEM Block Found")
+	}
+
+	var (
+		parsed interface{}
+		err    error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("Unsupported PEM Block: %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := new(Key)
+	if signer, ok := parsed.(crypto.Signer); ok {
+		key.Private = signer
+		key.Public = signer.Public()
+	} else {
+		key.Public = parsed
+	}
+
+	key.Algorithm, err = algorithmFor(key.Public)
+	if err != nil {
+		return nil, err
+	}
+	key.Method = jwt.GetSigningMethod(key.Algorithm)
+
+	key.ID, err = Thumbprint(key.Public)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func algorithmFor(public interface{}) (string, error) {
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return "RS256", nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return "ES256", nil
+		case elliptic.P384():
+			return "ES384", nil
+		case elliptic.P521():
+			return "ES512", nil
+		}
+		return "", fmt.Errorf("Unsupported Curve: %s", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "EdDSA", nil
+	}
+	return "", fmt.Errorf("Unsupported Key Type: %T", public)
+}
+
+func publicJWK(public interface{}) (JWK, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	switch k := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{KTY: "RSA", N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())}, nil
+	case *ecdsa.PublicKey:
+		// Coordinates Are Padded To The Curve Size
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return JWK{KTY: "EC", CRV: k.Curve.Params().Name, X: b64(k.X.FillBytes(make([]byte, size))), Y: b64(k.Y.FillBytes(make([]byte, size)))}, nil
+	case ed25519.PublicKey:
+		return JWK{KTY: "OKP", CRV: "Ed25519", X: b64(k)}, nil
+	}
+	return JWK{}, fmt.Errorf("Unsupported Key Type: %T", public)
+}
+
+// RFC 7638 - SHA-256 Of The Required Members In Lexicographic Order
+func Thumbprint(public interface{}) (string, error) {
+	jwk, err := publicJWK(public)
+	if err != nil {
+		return "", err
+	}
+
+	var members string
+	switch jwk.KTY {
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, jwk.CRV, jwk.X, jwk.Y)
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, jwk.CRV, jwk.X)
+	}
+	sum := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
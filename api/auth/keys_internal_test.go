@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/config"
+)
+
+// Keys That Never Loaded Fail The Request Rather Than The Process
+func TestKeysUnavailable(t *testing.T) {
+	previous, ring := config.Get(), keyRing.Load()
+	t.Cleanup(func() {
+		config.Set(previous)
+		keyRing.Store(ring)
+	})
+	cfg := config.Default()
+	cfg.Auth.SigningAlg = "EdDSA"
+	cfg.Auth.SigningKeyFile = filepath.Join(t.TempDir(), "missing.pem")
+	config.Set(cfg)
+	keyRing.Store(nil)
+
+	_, err := Keys()
+	if !errors.Is(err, ErrKeysUnavailable) {
+		t.Fatalf("\nUnexpected Error: %v\n", err)
+	}
+	// Nothing Broken Is Cached So A Fixed Config Is Picked Up
+	if keyRing.Load() != nil {
+		t.Fatalf("\nFailed Key Ring Was Stored\n")
+	}
+
+	app := fiber.New()
+	app.Get("/jwks", JWKS)
+	app.Get("/private", ValidateJWT, func(c *fiber.Ctx) error {
+		return c.SendStatus(200)
+	})
+	for _, path := range []string{"/jwks", "/private"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer a.b.c")
+		res, err := app.Test(req, -1)
+		if err != nil || res.StatusCode != 500 {
+			t.Fatalf("\nGET %s: %v %v Expected: 500\n", path, res.StatusCode, err)
+		}
+	}
+}
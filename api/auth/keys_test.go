@@ -0,0 +1,175 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"app/api/auth"
+	"app/config"
+	"app/testutil"
+)
+
+// Writes A PKCS#8 Private Key And Its PKIX Public Key - Returns Both Paths
+func writeKey(t *testing.T, signer crypto.Signer) (string, string) {
+	dir := t.TempDir()
+
+	private, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		t.Fatalf("\nFailed To Marshal Private Key: %s\n", err.Error())
+	}
+	public, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		t.Fatalf("\nFailed To Marshal Public Key: %s\n", err.Error())
+	}
+
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+	os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: private}), 0600)
+	os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public}), 0644)
+	return privatePath, publicPath
+}
+
+func generateKeys(t *testing.T) map[string]crypto.Signer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("\nFailed To Generate RSA Key: %s\n", err.Error())
+	}
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	return map[string]crypto.Signer{"RS256": rsaKey, "ES256": ecKey, "EdDSA": edKey}
+}
+
+func parse(ring *auth.KeyRing, token string) error {
+	_, err := jwt.Parse(token, ring.Keyfunc, jwt.WithValidMethods(ring.Methods()))
+	return err
+}
+
+func TestKeyRingSignAndVerify(t *testing.T) {
+	for alg, signer := range generateKeys(t) {
+		t.Run(alg, func(t *testing.T) {
+			private, _ := writeKey(t, signer)
+
+			ring, err := auth.NewKeyRing(config.AuthConfig{SigningAlg: alg, SigningKeyFile: private})
+			if err != nil {
+				t.Fatalf("\nFailed To Load Key Ring: %s\n", err.Error())
+			}
+
+			token, err := ring.Sign(jwt.MapClaims{"sub": "1"})
+			if err != nil {
+				t.Fatalf("\nFailed To Sign: %s\n", err.Error())
+			}
+			parsed, _, _ := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+			if parsed.Header["kid"] != ring.Signing.ID || parsed.Header["alg"] != alg {
+				t.Fatalf("\nInvalid Header: %v\n", parsed.Header)
+			}
+
+			err = parse(ring, token)
+			if err != nil {
+				t.Fatalf("\nFailed To Verify: %s\n", err.Error())
+			}
+
+			jwks := ring.JWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].KID != ring.Signing.ID || jwks.Keys[0].Alg != alg {
+				t.Fatalf("\nInvalid JWKS: %+v\n", jwks)
+			}
+		})
+	}
+}
+
+func TestKeyRingRejectsMismatchedKey(t *testing.T) {
+	keys := generateKeys(t)
+	private, _ := writeKey(t, keys["ES256"])
+
+	_, err := auth.NewKeyRing(config.AuthConfig{SigningAlg: "RS256", SigningKeyFile: private})
+	if err == nil {
+		t.Fatalf("\nExpected An ES256 Key To Be Refused For RS256\n")
+	}
+
+	_, public := writeKey(t, keys["ES256"])
+	_, err = auth.NewKeyRing(config.AuthConfig{SigningAlg: "ES256", SigningKeyFile: public})
+	if err == nil {
+		t.Fatalf("\nExpected A Public Key To Be Refused For Signing\n")
+	}
+}
+
+// Tokens From The Old Key Keep Working While It's A Verification Key
+func TestKeyRingRotation(t *testing.T) {
+	keys := generateKeys(t)
+	oldPrivate, oldPublic := writeKey(t, keys["ES256"])
+	newPrivate, _ := writeKey(t, keys["EdDSA"])
+
+	oldRing, _ := auth.NewKeyRing(config.AuthConfig{SigningAlg: "ES256", SigningKeyFile: oldPrivate})
+	rotated, err := auth.NewKeyRing(config.AuthConfig{SigningAlg: "EdDSA", SigningKeyFile: newPrivate, VerificationKeyFiles: []string{oldPublic}})
+	if err != nil {
+		t.Fatalf("\nFailed To Load Rotated Key Ring: %s\n", err.Error())
+	}
+
+	oldToken, _ := oldRing.Sign(jwt.MapClaims{"sub": "1"})
+	newToken, _ := rotated.Sign(jwt.MapClaims{"sub": "1"})
+
+	if err := parse(rotated, oldToken); err != nil {
+		t.Fatalf("\nOld Token Rejected After Rotation: %s\n", err.Error())
+	}
+	if err := parse(rotated, newToken); err != nil {
+		t.Fatalf("\nNew Token Rejected: %s\n", err.Error())
+	}
+	if err := parse(oldRing, newToken); err == nil {
+		t.Fatalf("\nExpected Unknown kid To Be Rejected\n")
+	}
+	if len(rotated.JWKS().Keys) != 2 {
+		t.Fatalf("\nExpected Both Keys In The JWKS: %+v\n", rotated.JWKS())
+	}
+
+	// Once The Old Key Is Dropped Its Tokens Stop Working
+	retired, _ := auth.NewKeyRing(config.AuthConfig{SigningAlg: "EdDSA", SigningKeyFile: newPrivate})
+	if err := parse(retired, oldToken); err == nil {
+		t.Fatalf("\nExpected Retired Key To Be Rejected\n")
+	}
+}
+
+// A Shared Secret Is Never Published
+func TestKeyRingSharedSecret(t *testing.T) {
+	ring, err := auth.NewKeyRing(config.AuthConfig{SigningAlg: "HS384", JWTSecret: "secret"})
+	if err != nil {
+		t.Fatalf("\nFailed To Load Key Ring: %s\n", err.Error())
+	}
+	if len(ring.JWKS().Keys) != 0 {
+		t.Fatalf("\nShared Secret Leaked Into JWKS\n")
+	}
+}
+
+func TestJWKSEndpoint(t *testing.T) {
+	private, _ := writeKey(t, generateKeys(t)["EdDSA"])
+	cfg := testutil.Config(t)
+	cfg.Auth.SigningAlg = "EdDSA"
+	cfg.Auth.SigningKeyFile = private
+	app := testutil.SetupWith(t, cfg)
+
+	res, body := testutil.Request(t, app, http.MethodGet, "/.well-known/jwks.json", nil, "")
+	testutil.ExpectStatus(t, res, body, 200)
+
+	jwks := new(auth.JWKSet)
+	testutil.Decode(t, body, jwks)
+	if len(jwks.Keys) != 1 || jwks.Keys[0].KTY != "OKP" || jwks.Keys[0].CRV != "Ed25519" {
+		t.Fatalf("\nInvalid JWKS: %s\n", body)
+	}
+
+	// Tokens Signed With The Key Work End To End
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/database"
+	"app/metrics"
+	"app/tracing"
+)
+
+/*
+Long Lived Token For Scripts And Services - uap_<prefix>_<secret>
+The Prefix Is Stored In The Clear To Find And Identify The Token,
+Only A SHA-256 Hash Of The Whole Token Is Kept
+*/
+type PersonalAccessToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"type:VARCHAR(64);not null"`
+	Prefix     string     `json:"prefix" gorm:"type:VARCHAR(16);not null;uniqueIndex"`
+	TokenHash  string     `json:"-" gorm:"type:VARCHAR(64);not null"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json;type:TEXT;not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// Marks Our Tokens So Secret Scanners And Authenticate Can Spot Them
+const PersonalAccessTokenPrefix = "uap_"
+
+// How Often last_used_at Is Written - Saves A Write On Every Request
+const lastUsedResolution = time.Minute
+
+// Returns The Raw Token - It Can't Be Recovered Later
+func IssuePersonalAccessToken(ctx context.Context, userID uint, name string, scopes []string, expiresAt time.Time) (string, *PersonalAccessToken, error) {
+	b := make([]byte, 4)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", nil, err
+	}
+	prefix := hex.EncodeToString(b)
+	secret, err := RandomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+	raw := PersonalAccessTokenPrefix + prefix + "_" + secret
+
+	if scopes == nil {
+		scopes = []string{}
+	}
+	token := &PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    prefix,
+		TokenHash: HashToken(raw),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	err = database.DB.WithContext(ctx).Create(token).Error
+	if err != nil {
+		return "", nil, err
+	}
+	metrics.TokensIssued.WithLabelValues(metrics.TokenPersonalAccess).Inc()
+	return raw, token, nil
+}
+
+// Looks Up A Raw Token And Checks It's Still Usable
+func ParsePersonalAccessToken(ctx context.Context, raw string) (*PersonalAccessToken, error) {
+	prefix, secret, found := strings.Cut(strings.TrimPrefix(raw, PersonalAccessTokenPrefix), "_")
+	if !strings.HasPrefix(raw, PersonalAccessTokenPrefix) || !found || prefix == "" || secret == "" {
+		return nil, ErrTokenMalformed
+	}
+
+	db := database.DB.WithContext(ctx)
+	var token PersonalAccessToken
+	res := db.Where("prefix = ?", prefix).Limit(1).Find(&token)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 || subtle.ConstantTimeCompare([]byte(token.TokenHash), []byte(HashToken(raw))) != 1 {
+		return nil, ErrTokenUnknown
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil {
+		return nil, ErrTokenRevoked
+	}
+	if !now.Before(token.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedResolution {
+		db.Model(&token).Update("last_used_at", now)
+	}
+	return &token, nil
+}
+
+// Revokes One Of The User's Tokens - False If They Have No Such Live Token
+func RevokePersonalAccessToken(ctx context.Context, id uint, userID uint) (bool, error) {
+	res := database.DB.WithContext(ctx).Model(&PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+// Revokes Every Personal Access Token The User Holds - For When The Account May Be Compromised
+func RevokePersonalAccessTokens(ctx context.Context, userID uint) error {
+	return database.DB.WithContext(ctx).Model(&PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+/*
+Accepts A Bearer JWT Or A Personal Access Token - Use In Place Of ValidateJWT
+Token Requests Always Carry Their Scopes In Locals, Even When Empty,
+So RequirePermission Never Treats Them As Unscoped
+*/
+func Authenticate(c *fiber.Ctx) error {
+	raw, ok := bearerToken(c)
+	if !ok || !strings.HasPrefix(raw, PersonalAccessTokenPrefix) {
+		return ValidateJWT(c)
+	}
+
+	ctx, span := tracing.Start(c.UserContext(), "auth.Authenticate")
+	token, err := ParsePersonalAccessToken(ctx, raw)
+	switch tokenValidated(span, metrics.TokenPersonalAccess, err) {
+	case resultValid:
+		span.End()
+	case resultError:
+		tracing.End(span, err)
+		return c.SendStatus(500)
+	default:
+		span.End()
+		return Unauthorized(c, err)
+	}
+
+	// Add Values To Locals
+	c.Locals("user_id", fmt.Sprintf("%d", token.UserID))
+	c.Locals("scopes", token.Scopes)
+	c.Locals("token_id", token.ID)
+	return c.Next()
+}
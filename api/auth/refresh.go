@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"app/config"
+	"app/database"
+	"app/metrics"
+)
+
+/*
+Opaque Refresh Tokens - Only A SHA-256 Hash Is Stored
+Every Rotation Issues A New Token In The Same Family, Presenting An
+Already Used Token Means It Leaked So The Whole Family Is Revoked
+*/
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	FamilyID  string     `json:"family_id" gorm:"type:VARCHAR(64);not null;index"`
+	TokenHash string     `json:"-" gorm:"type:VARCHAR(64);not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+var (
+	ErrRefreshTokenInvalid = errors.New("Invalid Refresh Token")
+	ErrRefreshTokenReused  = errors.New("Refresh Token Reused")
+)
+
+// Starts A New Token Family - Returns The Raw Token To Hand To The Client
+func IssueRefreshToken(ctx context.Context, userID uint) (string, error) {
+	familyID, err := RandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	return issueRefreshToken(database.DB.WithContext(ctx), userID, familyID)
+}
+
+func issueRefreshToken(db *gorm.DB, userID uint, familyID string) (string, error) {
+	raw, err := RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	token := RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: HashToken(raw),
+		ExpiresAt: time.Now().Add(time.Duration(config.Get().Auth.RefreshTokenExpires) * time.Second),
+	}
+	err = db.Create(&token).Error
+	if err != nil {
+		return "", err
+	}
+	metrics.TokensIssued.WithLabelValues(metrics.TokenRefresh).Inc()
+	return raw, nil
+}
+
+/*
+Exchanges A Refresh Token For A New One In The Same Family
+Returns The Owner So The Caller Can Issue A Fresh Access Token
+*/
+func RotateRefreshToken(ctx context.Context, raw string) (uint, string, error) {
+	userID, newRaw, err := rotateRefreshToken(ctx, raw)
+	tokenValidated(nil, metrics.TokenRefresh, err)
+	return userID, newRaw, err
+}
+
+func rotateRefreshToken(ctx context.Context, raw string) (uint, string, error) {
+	db := database.DB.WithContext(ctx)
+	var token RefreshToken
+	err := db.Where("token_hash = ?", HashToken(raw)).First(&token).Error
+	if err != nil {
+		return 0, "", ErrRefreshTokenInvalid
+	}
+
+	if token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		return 0, "", ErrRefreshTokenInvalid
+	}
+	if token.UsedAt != nil {
+		RevokeRefreshFamily(ctx, token.FamilyID)
+		return 0, "", ErrRefreshTokenReused
+	}
+
+	var newRaw string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Only One Concurrent Rotation Can Win
+		res := tx.Model(&RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		newRaw, err = issueRefreshToken(tx, token.UserID, token.FamilyID)
+		return err
+	})
+
+	if errors.Is(err, ErrRefreshTokenReused) {
+		RevokeRefreshFamily(ctx, token.FamilyID)
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return token.UserID, newRaw, nil
+}
+
+func RevokeRefreshFamily(ctx context.Context, familyID string) error {
+	return database.DB.WithContext(ctx).Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Revokes The Family Of A Refresh Token - Ignored Unless It Belongs To userID
+func RevokeRefreshToken(ctx context.Context, raw string, userID uint) error {
+	var token RefreshToken
+	err := database.DB.WithContext(ctx).Where("token_hash = ? AND user_id = ?", HashToken(raw), userID).First(&token).Error
+	if err != nil {
+		return ErrRefreshTokenInvalid
+	}
+	return RevokeRefreshFamily(ctx, token.FamilyID)
+}
+
+// Random URL Safe Token With n Bytes Of Entropy
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Tokens Are High Entropy So A Plain SHA-256 Is Enough At Rest
+func HashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"app/config"
+	"app/database"
+)
+
+// A Single Access Token Revoked By Logout - Safe To Purge Once It Would Have Expired Anyway
+type RevokedToken struct {
+	JTI       string    `gorm:"type:VARCHAR(64);primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+/*
+Logout Everywhere - Each One Bumps Generation, Tokens Carry The Generation
+They Were Issued In And Anything Older Is Rejected
+*/
+type UserTokenRevocation struct {
+	UserID        uint      `gorm:"primaryKey;autoIncrement:false"`
+	Generation    int64     `gorm:"not null;default:0"`
+	RevokedBefore time.Time `gorm:"not null"` // When It Last Happened
+}
+
+/*
+Revocations Live In The Database So Every Instance Sees Them
+Positive Hits Are Cached Until The Token Expires, Misses Only For
+auth.revocation_cache_ttl So Other Instances' Logouts Show Up Quickly
+*/
+type revocationCache struct {
+	mu     sync.Mutex
+	tokens map[string]tokenEntry
+	users  map[uint]userEntry
+}
+
+type tokenEntry struct {
+	revoked bool
+	until   time.Time // Entry Is Trusted Until Then
+}
+
+type userEntry struct {
+	generation int64
+	until      time.Time
+}
+
+// Keeps The Cache From Growing Without Bound
+const maxCacheEntries = 10000
+
+var revocations = &revocationCache{
+	tokens: map[string]tokenEntry{},
+	users:  map[uint]userEntry{},
+}
+
+// Forgets Everything Cached - Used When Switching Databases In Tests
+func ClearRevocationCache() {
+	revocations.mu.Lock()
+	defer revocations.mu.Unlock()
+	revocations.tokens = map[string]tokenEntry{}
+	revocations.users = map[uint]userEntry{}
+}
+
+func cacheTTL() time.Duration {
+	return time.Duration(config.Get().Auth.RevocationCacheTTL) * time.Second
+}
+
+// Revokes One Access Token By Its jti
+func RevokeToken(ctx context.Context, jti string, userID uint, expiresAt time.Time) error {
+	db := database.DB.WithContext(ctx)
+	err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}).Error
+	if err != nil {
+		return err
+	}
+
+	// Rows Past Their Expiry Can Never Match A Valid Token
+	db.Where("expires_at < ?", time.Now()).Delete(&RevokedToken{})
+
+	revocations.setToken(jti, tokenEntry{revoked: true, until: expiresAt})
+	return nil
+}
+
+/*
+Revokes Every Access And Refresh Token The User Holds - Personal Access
+Tokens Outlive It So Scripts Keep Working, See RevokePersonalAccessTokens
+*/
+func RevokeAllForUser(ctx context.Context, userID uint) error {
+	db := database.DB.WithContext(ctx)
+	now := time.Now()
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"generation":     gorm.Expr("user_token_revocations.generation + 1"),
+			"revoked_before": now,
+		}),
+	}).Create(&UserTokenRevocation{UserID: userID, Generation: 1, RevokedBefore: now}).Error
+	if err != nil {
+		return err
+	}
+
+	err = db.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+
+	// Next Check Reads The New Generation
+	revocations.mu.Lock()
+	delete(revocations.users, userID)
+	revocations.mu.Unlock()
+	return nil
+}
+
+// Reports Whether A Token With This jti, Owner And Generation Has Been Revoked
+func IsRevoked(ctx context.Context, jti string, userID uint, generation int64) (bool, error) {
+	revoked, err := tokenRevoked(ctx, jti)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	current, err := cachedGeneration(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return generation < current, nil
+}
+
+func tokenRevoked(ctx context.Context, jti string) (bool, error) {
+	now := time.Now()
+	if entry, ok := revocations.token(jti); ok && now.Before(entry.until) {
+		return entry.revoked, nil
+	}
+
+	var revoked RevokedToken
+	res := database.DB.WithContext(ctx).Where("jti = ?", jti).Limit(1).Find(&revoked)
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	if res.RowsAffected > 0 {
+		revocations.setToken(jti, tokenEntry{revoked: true, until: revoked.ExpiresAt})
+		return true, nil
+	}
+	revocations.setToken(jti, tokenEntry{revoked: false, until: now.Add(cacheTTL())})
+	return false, nil
+}
+
+func cachedGeneration(ctx context.Context, userID uint) (int64, error) {
+	now := time.Now()
+	if entry, ok := revocations.user(userID); ok && now.Before(entry.until) {
+		return entry.generation, nil
+	}
+
+	generation, err := CurrentGeneration(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	revocations.setUser(userID, userEntry{generation: generation, until: now.Add(cacheTTL())})
+	return generation, nil
+}
+
+// Stamp Into Tokens So Logout Everywhere Revokes Them - 0 When The User Never Has
+func CurrentGeneration(ctx context.Context, userID uint) (int64, error) {
+	var revocation UserTokenRevocation
+	err := database.DB.WithContext(ctx).Where("user_id = ?", userID).Limit(1).Find(&revocation).Error
+	return revocation.Generation, err
+}
+
+func (rc *revocationCache) token(jti string) (tokenEntry, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.tokens[jti]
+	return entry, ok
+}
+
+func (rc *revocationCache) setToken(jti string, entry tokenEntry) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.tokens) >= maxCacheEntries {
+		rc.tokens = prune(rc.tokens, func(e tokenEntry) time.Time { return e.until })
+	}
+	rc.tokens[jti] = entry
+}
+
+func (rc *revocationCache) user(userID uint) (userEntry, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.users[userID]
+	return entry, ok
+}
+
+func (rc *revocationCache) setUser(userID uint, entry userEntry) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.users) >= maxCacheEntries {
+		rc.users = prune(rc.users, func(e userEntry) time.Time { return e.until })
+	}
+	rc.users[userID] = entry
+}
+
+// Drops Stale Entries - Starts Over If Everything Is Still Fresh
+func prune[K comparable, V any](m map[K]V, until func(V) time.Time) map[K]V {
+	now := time.Now()
+	for k, v := range m {
+		if now.After(until(v)) {
+			delete(m, k)
+		}
+	}
+	if len(m) >= maxCacheEntries {
+		return map[K]V{}
+	}
+	return m
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/*
+RFC 6238 Time-Based One-Time Passwords - SHA-1, 6 Digits, 30 Second Steps
+The Only Parameters Every Authenticator App Supports
+*/
+const (
+	TOTPPeriod = 30
+	TOTPDigits = 6
+	// Codes From One Step Either Side Are Accepted For Clock Drift
+	TOTPSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// 160 Bit Secret, Base32 As Authenticator Apps Expect
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// otpauth:// URI For QR Codes - label Is Usually The Username
+func TOTPURI(issuer string, label string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprintf("%d", TOTPDigits))
+	query.Set("period", fmt.Sprintf("%d", TOTPPeriod))
+
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + label,
+		RawQuery: query.Encode(),
+	}).String()
+}
+
+// RFC 4226 HOTP Value For counter
+func HOTP(key []byte, counter uint64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// Dynamic Truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / TOTPPeriod
+}
+
+/*
+Checks code Against The Steps Around now - Returns The Step It Matched
+So Callers Can Refuse A Code That Was Already Used
+*/
+func ValidateTOTP(secret string, code string, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return 0, false
+	}
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := TOTPStep(now)
+	for step := current - TOTPSkew; step <= current+TOTPSkew; step++ {
+		expected := HOTP(key, uint64(step), TOTPDigits)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
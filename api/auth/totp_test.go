@@ -0,0 +1,77 @@
+package auth_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"app/api/auth"
+)
+
+// RFC 6238 Appendix B - SHA-1 Vectors
+func TestHOTPVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	cases := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tc := range cases {
+		code := auth.HOTP(key, uint64(tc.unix/auth.TOTPPeriod), 8)
+		if code != tc.expected {
+			t.Fatalf("\nUnexpected Code At %d: %s Expected: %s\n", tc.unix, code, tc.expected)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("\nFailed To Generate Secret: %s\n", err.Error())
+	}
+	key, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	now := time.Now()
+	step := auth.TOTPStep(now)
+
+	cases := []struct {
+		name  string
+		code  string
+		valid bool
+	}{
+		{"Current", auth.HOTP(key, uint64(step), 6), true},
+		{"Previous Step", auth.HOTP(key, uint64(step-1), 6), true},
+		{"Next Step", auth.HOTP(key, uint64(step+1), 6), true},
+		{"Too Old", auth.HOTP(key, uint64(step-3), 6), false},
+		{"Wrong Length", "12345", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, valid := auth.ValidateTOTP(secret, tc.code, now)
+			if valid != tc.valid {
+				t.Fatalf("\nValid: %v Expected: %v\n", valid, tc.valid)
+			}
+		})
+	}
+
+	matched, _ := auth.ValidateTOTP(secret, auth.HOTP(key, uint64(step-1), 6), now)
+	if matched != step-1 {
+		t.Fatalf("\nUnexpected Step: %d Expected: %d\n", matched, step-1)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(auth.TOTPURI("user-auth", "tester", "JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatalf("\nInvalid URI: %s\n", err.Error())
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/user-auth:tester" || uri.Query().Get("secret") != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("\nUnexpected URI: %s\n", uri)
+	}
+}
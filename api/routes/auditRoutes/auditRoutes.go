@@ -0,0 +1,20 @@
+package auditRoutes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"app/api/auth"
+	"app/audit"
+	"app/models/user"
+	"app/ratelimit"
+)
+
+func SetAuditRoutes(api fiber.Router) {
+	auditGroup := api.Group("/audit")
+
+	authed := ratelimit.Policy("authenticated")
+
+	auditGroup.Get("/events", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermAuditRead), audit.GetEvents)
+	// Reads The Whole Log - Kept Behind The Same Permission As Searching It
+	auditGroup.Get("/verify", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermAuditRead), audit.VerifyChain)
+}
@@ -5,19 +5,60 @@ import (
 
 	"app/api/auth"
 	"app/models/user"
+	"app/ratelimit"
 )
 
 func SetUserRoutes(api fiber.Router) {
 	userGroup := api.Group("/user")
-	userGroup.Post("/login", user.Login)
-	userGroup.Post("/create", user.CreateUser)
-	userGroup.Get("/", auth.ValidateJWT, user.VerifyAccountEnabled, user.GetUser)
-	userGroup.Put("/update-user", auth.ValidateJWT, user.VerifyAccountEnabled, user.UpdateUser)
-	userGroup.Put("/update-password", auth.ValidateJWT, user.VerifyAccountEnabled, user.UpdatePassword)
-	userGroup.Delete("/", auth.ValidateJWT, user.VerifyAccountEnabled, user.DeleteUser)
+
+	// Rate Limit Policies - See rate_limit.policies
+	login := ratelimit.Policy("login")
+	signup := ratelimit.Policy("signup")
+	email := ratelimit.Policy("email")
+	public := ratelimit.Policy("public")
+	// Counted Per Token Or User So It Has To Follow Authentication
+	authed := ratelimit.Policy("authenticated")
+
+	userGroup.Post("/login", login, user.Login)
+	userGroup.Post("/login/mfa", login, user.LoginMFA)
+	userGroup.Post("/webauthn/login/begin", login, user.BeginWebAuthnLogin)
+	userGroup.Post("/webauthn/login/finish", login, user.FinishWebAuthnLogin)
+	userGroup.Post("/create", signup, user.CreateUser)
+	userGroup.Post("/token/refresh", public, user.RefreshToken)
+	userGroup.Post("/password/forgot", email, user.ForgotPassword)
+	userGroup.Post("/password/reset", public, user.ResetPassword)
+	userGroup.Post("/email/verify", public, user.VerifyEmail)
+	userGroup.Post("/email/resend", email, user.ResendVerification)
+	userGroup.Get("/", auth.Authenticate, authed, user.VerifyAccountEnabled, user.GetUser)
+	userGroup.Put("/update-user", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.UpdateUser)
+	userGroup.Put("/update-password", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.UpdatePassword)
+	userGroup.Delete("/", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.DeleteUser)
+	userGroup.Post("/logout", auth.ValidateJWT, authed, user.Logout)
+	userGroup.Post("/logout-all", auth.ValidateJWT, authed, user.LogoutAll)
+	userGroup.Post("/mfa/totp/enroll", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.EnrollTOTP)
+	userGroup.Post("/mfa/totp/confirm", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.ConfirmTOTP)
+	userGroup.Post("/mfa/totp/disable", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.DisableTOTP)
+	userGroup.Post("/mfa/recovery-codes", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.RegenerateRecoveryCodes)
+	userGroup.Post("/webauthn/register/begin", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.BeginWebAuthnRegistration)
+	userGroup.Post("/webauthn/register/finish", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.FinishWebAuthnRegistration)
+	userGroup.Get("/webauthn/credentials", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.GetWebAuthnCredentials)
+	userGroup.Put("/webauthn/credentials/:id", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.RenameWebAuthnCredential)
+	userGroup.Delete("/webauthn/credentials/:id", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.DeleteWebAuthnCredential)
+	// Managing Tokens Needs A Login So One Leaked Token Can't Mint Or Revoke Others
+	userGroup.Post("/tokens", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.CreatePersonalAccessToken)
+	userGroup.Get("/tokens", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.GetPersonalAccessTokens)
+	userGroup.Delete("/tokens/:id", auth.ValidateJWT, authed, user.VerifyAccountEnabled, user.RevokePersonalAccessToken)
 
 	// Admin Functions
-	userGroup.Put("/admin-user-update", auth.ValidateJWT, auth.ValidateAdmin, user.VerifyAccountEnabled, user.AdminUpdateUser)
-	userGroup.Get("/getall", auth.ValidateJWT, auth.ValidateAdmin, user.VerifyAccountEnabled, user.GetAll)
-	userGroup.Get("/get-user-roles", auth.ValidateJWT, auth.ValidateAdmin, user.VerifyAccountEnabled, user.GetUserRoles)
+	userGroup.Put("/admin-user-update", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermUsersWrite), user.AdminUpdateUser)
+	userGroup.Post("/admin-unlock", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermUsersWrite), user.AdminUnlockUser)
+	userGroup.Get("/getall", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermUsersRead), user.GetAll)
+	userGroup.Get("/get-user-roles", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermRolesRead), user.GetUserRoles)
+	userGroup.Get("/roles", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermRolesRead), user.GetRoles)
+	userGroup.Post("/roles", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermRolesWrite), user.CreateRole)
+	userGroup.Put("/roles/:id", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermRolesWrite), user.UpdateRole)
+	userGroup.Delete("/roles/:id", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermRolesWrite), user.DeleteRole)
+	userGroup.Post("/roles/:id/permissions", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermRolesWrite), user.AttachRolePermissions)
+	userGroup.Delete("/roles/:id/permissions", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermRolesWrite), user.DetachRolePermissions)
+	userGroup.Get("/permissions", auth.Authenticate, authed, user.VerifyAccountEnabled, user.RequirePermission(user.PermRolesRead), user.GetPermissions)
 }
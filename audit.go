@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"app/api/auth"
+	"app/audit"
+	"app/config"
+	"app/database"
+)
+
+func auditCommand(cfg *config.Config, args []string) {
+	if len(args) == 0 || args[0] != "verify" {
+		usage()
+		os.Exit(2)
+	}
+
+	// Checkpoints Are Checked Against The Token Signing Keys
+	config.Set(cfg)
+	err := auth.LoadKeys(cfg.Auth)
+	if err != nil {
+		log.Fatalf("Unable To Load Signing Keys: %v", err)
+	}
+	db, err := database.Open(cfg.DB)
+	if err != nil {
+		log.Fatalf("Failed To Connect To Database: %v", err)
+	}
+
+	report, err := audit.Verify(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Checked %d Event(s), %d Before The Chain Began, %d Checkpoint(s)\n", report.Events, report.Unchained, report.Checkpoints)
+	if report.LastCheckpoint != 0 {
+		fmt.Printf("Signed Up To Event %d\n", report.LastCheckpoint)
+	}
+	if !report.Valid {
+		fmt.Printf("Broken At Event %d: %s\n", report.BrokenAt, report.Reason)
+		os.Exit(1)
+	}
+	fmt.Println("Chain Intact")
+}
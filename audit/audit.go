@@ -0,0 +1,154 @@
+/*
+Append Only Record Of Security Relevant Events - Who Did What To Whom,
+From Where, And What Changed. Handlers Call Record Once The Change Has Stuck
+Events Are Hash Chained With Signed Checkpoints - See chain.go And Verify
+*/
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/database"
+	"app/logging"
+	"app/metrics"
+)
+
+// Event Types - Grouped By What They Happen To
+const (
+	LoginSucceeded  = "login.succeeded"
+	LoginFailed     = "login.failed"
+	AccountLocked   = "account.locked"
+	UserCreated     = "user.created"
+	UserUpdated     = "user.updated"
+	UserDeleted     = "user.deleted"
+	PasswordChanged = "password.changed"
+	PasswordReset   = "password.reset"
+	EmailVerified   = "email.verified"
+	SessionsRevoked = "sessions.revoked"
+	MFAEnabled      = "mfa.enabled"
+	MFADisabled     = "mfa.disabled"
+	PasskeyAdded    = "passkey.added"
+	PasskeyRemoved  = "passkey.removed"
+	TokenCreated    = "token.created"
+	TokenRevoked    = "token.revoked"
+
+	AdminUserUpdated  = "admin.user_updated"
+	AdminUserUnlocked = "admin.user_unlocked"
+
+	RoleCreated            = "role.created"
+	RoleUpdated            = "role.updated"
+	RoleDeleted            = "role.deleted"
+	RolePermissionsChanged = "role.permissions_changed"
+)
+
+// What An Event's TargetID Refers To
+const (
+	TargetUser  = "user"
+	TargetRole  = "role"
+	TargetToken = "token"
+)
+
+/*
+One Recorded Event - ActorID Is Who Made The Request, Nil When Nobody Was
+Signed In. Changes Holds Only The Fields That Differ, Secrets Redacted
+Hash Covers The Event And PrevHash, The Hash Of The Event Before It
+*/
+type Event struct {
+	ID         uint                   `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time              `json:"created_at" gorm:"not null;index"`
+	Type       string                 `json:"type" gorm:"type:VARCHAR(64);not null;index"`
+	ActorID    *uint                  `json:"actor_id" gorm:"index"`
+	TargetType string                 `json:"target_type" gorm:"type:VARCHAR(32);index:idx_audit_target"`
+	TargetID   *uint                  `json:"target_id" gorm:"index:idx_audit_target"`
+	IP         string                 `json:"ip" gorm:"type:VARCHAR(45)"`
+	UserAgent  string                 `json:"user_agent" gorm:"type:VARCHAR(255)"`
+	RequestID  string                 `json:"request_id" gorm:"type:VARCHAR(64);index"`
+	Changes    map[string]Change      `json:"changes,omitempty" gorm:"serializer:json;type:TEXT"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty" gorm:"serializer:json;type:TEXT"`
+	PrevHash   string                 `json:"prev_hash" gorm:"type:VARCHAR(64);not null;default:''"`
+	Hash       string                 `json:"hash" gorm:"type:VARCHAR(64);not null;default:''"`
+}
+
+func (Event) TableName() string {
+	return "audit_events"
+}
+
+/*
+What Happened - Before And After Are Compared Field By Field, Either May
+Be Nil For Things Created Or Deleted. ActorID Defaults To The Signed In User
+*/
+type Entry struct {
+	Type       string
+	ActorID    uint
+	TargetType string
+	TargetID   uint
+	Before     interface{}
+	After      interface{}
+	Metadata   map[string]interface{}
+}
+
+/*
+Stores The Event With Who, Where And Which Request From c
+Failures Are Logged And Counted In audit_events_dropped_total Rather Than
+Returned - The Change Has Already Happened
+*/
+func Record(c *fiber.Ctx, e Entry) {
+	event := Event{
+		Type:       e.Type,
+		ActorID:    optional(e.ActorID),
+		TargetType: e.TargetType,
+		TargetID:   optional(e.TargetID),
+		IP:         truncate(c.IP(), 45),
+		UserAgent:  truncate(c.Get(fiber.HeaderUserAgent), 255),
+	}
+	event.RequestID = truncate(logging.RequestIDFrom(c), 64)
+	if event.ActorID == nil {
+		actor_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+		if err == nil {
+			event.ActorID = optional(uint(actor_id))
+		}
+	}
+
+	changes, err := Diff(e.Before, e.After)
+	if err != nil {
+		logging.For(c).Error("Audit Error: Failed To Compare", "type", e.Type, "error", err)
+	}
+	if len(changes) != 0 {
+		event.Changes = changes
+	}
+	event.Metadata, err = normalize(redactMap(e.Metadata))
+	if err != nil {
+		logging.For(c).Error("Audit Error: Failed To Encode Metadata", "type", e.Type, "error", err)
+	}
+
+	err = appendEvent(database.For(c), &event)
+	if err != nil {
+		metrics.AuditEventsDropped.WithLabelValues(e.Type).Inc()
+		logging.For(c).Error("Audit Error: Failed To Record", "type", e.Type, "error", err)
+	}
+}
+
+func optional(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// Cuts Client Supplied Values Down To Their Column Without Splitting A Character
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
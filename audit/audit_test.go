@@ -0,0 +1,154 @@
+package audit_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/audit"
+	"app/models/user"
+	"app/testutil"
+)
+
+type eventsResponse struct {
+	Events []audit.Event `json:"events"`
+	Next   uint          `json:"next"`
+}
+
+func events(t *testing.T, app *fiber.App, query string, authorization string) eventsResponse {
+	t.Helper()
+	res, body := testutil.Request(t, app, http.MethodGet, "/audit/events"+query, nil, authorization)
+	testutil.ExpectStatus(t, res, body, 200)
+	var response eventsResponse
+	testutil.Decode(t, body, &response)
+	return response
+}
+
+func TestDiff(t *testing.T) {
+	before := fiber.Map{"email": "a@test.com", "phone": "", "password": "old", "updated_at": 1}
+	after := fiber.Map{"email": "b@test.com", "phone": "", "password": "new", "updated_at": 2, "token_hash": "abc", "reset_token": "xyz"}
+
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		t.Fatalf("\nDiff Failed: %s\n", err.Error())
+	}
+	if len(changes) != 4 {
+		t.Fatalf("\nUnexpected Changes: %v\n", changes)
+	}
+	if changes["email"].From != "a@test.com" || changes["email"].To != "b@test.com" {
+		t.Fatalf("\nUnexpected Email Change: %v\n", changes["email"])
+	}
+	// Secrets Show They Changed But Not What To
+	if changes["password"].From != audit.Redacted || changes["password"].To != audit.Redacted {
+		t.Fatalf("\nPassword Wasn't Redacted: %v\n", changes["password"])
+	}
+	if changes["token_hash"].From != nil || changes["token_hash"].To != audit.Redacted {
+		t.Fatalf("\nToken Hash Wasn't Redacted: %v\n", changes["token_hash"])
+	}
+	if changes["reset_token"].To != audit.Redacted {
+		t.Fatalf("\nToken Wasn't Redacted: %v\n", changes["reset_token"])
+	}
+}
+
+func TestLoginEvents(t *testing.T) {
+	app := testutil.Setup(t)
+	tester := testutil.CreateUser(t, "tester", "123", "default")
+	testutil.CreateUser(t, "admin", "123", "admin")
+	admin := testutil.Login(t, app, "admin", "123")
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "wrong"}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "nobody", Password: "wrong"}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+	testutil.Login(t, app, "tester", "123")
+
+	failed := events(t, app, "?type="+audit.LoginFailed, admin).Events
+	if len(failed) != 2 {
+		t.Fatalf("\nUnexpected Failed Logins: %d Expected: 2\n", len(failed))
+	}
+	// Newest First - The Unknown Username Has No Target
+	if failed[0].TargetID != nil || failed[0].Metadata["username"] != "nobody" || failed[0].Metadata["reason"] != "unknown_user" {
+		t.Fatalf("\nUnexpected Event: %+v\n", failed[0])
+	}
+	if failed[1].TargetID == nil || *failed[1].TargetID != tester.ID || failed[1].ActorID != nil {
+		t.Fatalf("\nUnexpected Event: %+v\n", failed[1])
+	}
+	// Tied To The Response That Reported It
+	if failed[0].RequestID == "" || failed[0].RequestID != res.Header.Get(fiber.HeaderXRequestID) {
+		t.Fatalf("\nUnexpected Request ID: %q Expected: %q\n", failed[0].RequestID, res.Header.Get(fiber.HeaderXRequestID))
+	}
+
+	succeeded := events(t, app, fmt.Sprintf("?type=%s&actor_id=%d", audit.LoginSucceeded, tester.ID), admin).Events
+	if len(succeeded) != 1 || succeeded[0].Metadata["method"] != "password" {
+		t.Fatalf("\nUnexpected Successful Logins: %+v\n", succeeded)
+	}
+}
+
+func TestAdminUpdateRecorded(t *testing.T) {
+	app := testutil.Setup(t)
+	tester := testutil.CreateUser(t, "tester", "123", "default")
+	admin := testutil.CreateUser(t, "admin", "123", "admin")
+	authorization := testutil.Login(t, app, "admin", "123")
+
+	disabled := false
+	res, body := testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", user.AdminUserUpdateRequest{
+		UserID:          tester.ID,
+		RoleID:          testutil.Role(t, "admin").ID,
+		Email:           tester.Email,
+		Account_enabled: &disabled,
+	}, authorization)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	recorded := events(t, app, fmt.Sprintf("?target_type=user&target_id=%d", tester.ID), authorization).Events
+	if len(recorded) != 1 || recorded[0].Type != audit.AdminUserUpdated {
+		t.Fatalf("\nUnexpected Events: %+v\n", recorded)
+	}
+	event := recorded[0]
+	if event.ActorID == nil || *event.ActorID != admin.ID {
+		t.Fatalf("\nUnexpected Actor: %v Expected: %d\n", event.ActorID, admin.ID)
+	}
+	if len(event.Changes) != 2 {
+		t.Fatalf("\nUnexpected Changes: %+v\n", event.Changes)
+	}
+	if event.Changes["account_enabled"].From != true || event.Changes["account_enabled"].To != false {
+		t.Fatalf("\nUnexpected account_enabled Change: %+v\n", event.Changes["account_enabled"])
+	}
+	if _, ok := event.Changes["role_id"]; !ok {
+		t.Fatalf("\nRole Change Wasn't Recorded: %+v\n", event.Changes)
+	}
+}
+
+func TestGetEvents(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	testutil.CreateUser(t, "admin", "123", "admin")
+	admin := testutil.Login(t, app, "admin", "123")
+
+	// Needs audit:read
+	tester := testutil.Login(t, app, "tester", "123")
+	res, body := testutil.Request(t, app, http.MethodGet, "/audit/events", nil, tester)
+	testutil.ExpectStatus(t, res, body, 403)
+
+	for _, query := range []string{"?actor_id=x", "?since=yesterday", "?limit=0", "?limit=1000"} {
+		res, body = testutil.Request(t, app, http.MethodGet, "/audit/events"+query, nil, admin)
+		testutil.ExpectStatus(t, res, body, 400)
+	}
+
+	// Paged Newest First
+	first := events(t, app, "?limit=1", admin)
+	if len(first.Events) != 1 || first.Next != first.Events[0].ID {
+		t.Fatalf("\nUnexpected First Page: %+v\n", first)
+	}
+	second := events(t, app, fmt.Sprintf("?limit=1&before=%d", first.Next), admin)
+	if len(second.Events) != 1 || second.Events[0].ID >= first.Next {
+		t.Fatalf("\nUnexpected Second Page: %+v\n", second)
+	}
+
+	// Nothing Recorded Before Any Request
+	none := events(t, app, "?until=2000-01-01T00:00:00Z", admin)
+	if len(none.Events) != 0 || none.Next != 0 {
+		t.Fatalf("\nUnexpected Events: %+v\n", none)
+	}
+}
@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+	Hash Chain
+*/
+
+/*
+The Newest Chained Event - Every Append Moves It On Only If Nobody Else
+Has Since, So Concurrent Appends From Any Instance Can't Fork The Chain
+*/
+type ChainHead struct {
+	ID      uint   `gorm:"primaryKey"`
+	EventID uint   `gorm:"not null;default:0"`
+	Hash    string `gorm:"type:VARCHAR(64);not null;default:''"`
+}
+
+func (ChainHead) TableName() string {
+	return "audit_chain_heads"
+}
+
+// The Only Row In audit_chain_heads
+const chainHeadID = 1
+
+// How Often An Append Starts Again After Losing The Race For The Head
+const appendAttempts = 5
+
+var errChainMoved = errors.New("Audit Chain Head Moved")
+
+// Serializes Appends Within This Instance So Only Other Instances Cause Retries
+var appending sync.Mutex
+
+/*
+What An Event's Hash Covers - Everything But Its ID And Hash
+JSON Objects Marshal With Sorted Keys So The Encoding Is Stable
+*/
+type chainedEvent struct {
+	PrevHash   string                 `json:"prev_hash"`
+	CreatedAt  string                 `json:"created_at"`
+	Type       string                 `json:"type"`
+	ActorID    *uint                  `json:"actor_id"`
+	TargetType string                 `json:"target_type"`
+	TargetID   *uint                  `json:"target_id"`
+	IP         string                 `json:"ip"`
+	UserAgent  string                 `json:"user_agent"`
+	RequestID  string                 `json:"request_id"`
+	Changes    map[string]Change      `json:"changes"`
+	Metadata   map[string]interface{} `json:"metadata"`
+}
+
+// Hex SHA-256 Of The Event Chained To Its PrevHash
+func (e *Event) ComputeHash() (string, error) {
+	b, err := json.Marshal(chainedEvent{
+		PrevHash:   e.PrevHash,
+		CreatedAt:  e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		Type:       e.Type,
+		ActorID:    e.ActorID,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		RequestID:  e.RequestID,
+		Changes:    e.Changes,
+		Metadata:   e.Metadata,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Links The Event To The Head Of The Chain And Stores It
+func appendEvent(db *gorm.DB, event *Event) error {
+	appending.Lock()
+	defer appending.Unlock()
+
+	// Every Database Keeps Milliseconds
+	event.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+
+	var err error
+	for attempt := 0; attempt < appendAttempts; attempt++ {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var head ChainHead
+			err := tx.FirstOrCreate(&head, ChainHead{ID: chainHeadID}).Error
+			if err != nil {
+				return err
+			}
+
+			event.ID = 0
+			event.PrevHash = head.Hash
+			event.Hash, err = event.ComputeHash()
+			if err != nil {
+				return err
+			}
+			err = tx.Create(event).Error
+			if err != nil {
+				return err
+			}
+
+			res := tx.Model(&ChainHead{}).
+				Where("id = ? AND hash = ?", chainHeadID, head.Hash).
+				Updates(map[string]interface{}{"event_id": event.ID, "hash": event.Hash})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errChainMoved
+			}
+			return nil
+		})
+		if !errors.Is(err, errChainMoved) {
+			return err
+		}
+	}
+	return err
+}
+
+// Round Trips Through JSON So What's Hashed Is Exactly What's Read Back
+func normalize(m map[string]interface{}) (map[string]interface{}, error) {
+	if m == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]interface{}
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
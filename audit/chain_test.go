@@ -0,0 +1,206 @@
+package audit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/gorm"
+
+	"app/audit"
+	"app/database"
+	"app/metrics"
+	"app/testutil"
+)
+
+// An App With A Few Chained Events - Returns Them Oldest First
+func chainedApp(t *testing.T) (*fiber.App, []audit.Event) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "admin", "123", "admin")
+	for i := 0; i < 4; i++ {
+		testutil.Login(t, app, "admin", "123")
+	}
+
+	var events []audit.Event
+	database.DB.Order("id").Find(&events)
+	if len(events) != 4 {
+		t.Fatalf("\nUnexpected Events: %d Expected: 4\n", len(events))
+	}
+	return app, events
+}
+
+func verify(t *testing.T) *audit.Report {
+	t.Helper()
+	report, err := audit.Verify(database.DB)
+	if err != nil {
+		t.Fatalf("\nVerify Failed: %s\n", err.Error())
+	}
+	return report
+}
+
+func expectBroken(t *testing.T, report *audit.Report, id uint, reason string) {
+	t.Helper()
+	if report.Valid || report.BrokenAt != id || !strings.Contains(report.Reason, reason) {
+		t.Fatalf("\nExpected Break At %d (%s) Got: %+v\n", id, reason, report)
+	}
+}
+
+func TestChainIntact(t *testing.T) {
+	app, events := chainedApp(t)
+
+	if events[0].PrevHash != "" || events[1].PrevHash != events[0].Hash {
+		t.Fatalf("\nEvents Aren't Chained: %+v\n", events[:2])
+	}
+
+	checkpoint, err := audit.CreateCheckpoint(database.DB)
+	if err != nil || checkpoint == nil || checkpoint.EventID != events[3].ID {
+		t.Fatalf("\nUnexpected Checkpoint: %+v %v\n", checkpoint, err)
+	}
+	// Nothing New To Sign
+	again, err := audit.CreateCheckpoint(database.DB)
+	if err != nil || again != nil {
+		t.Fatalf("\nUnexpected Second Checkpoint: %+v %v\n", again, err)
+	}
+
+	report := verify(t)
+	if !report.Valid || report.Events != 4 || report.Checkpoints != 1 || report.LastCheckpoint != events[3].ID {
+		t.Fatalf("\nUnexpected Report: %+v\n", report)
+	}
+
+	authorization := testutil.Login(t, app, "admin", "123")
+	res, body := testutil.Request(t, app, http.MethodGet, "/audit/verify", nil, authorization)
+	testutil.ExpectStatus(t, res, body, 200)
+	var response struct {
+		Report audit.Report `json:"report"`
+	}
+	testutil.Decode(t, body, &response)
+	if !response.Report.Valid || response.Report.Events != 5 {
+		t.Fatalf("\nUnexpected Report: %+v\n", response.Report)
+	}
+}
+
+func TestChainEdited(t *testing.T) {
+	_, events := chainedApp(t)
+	database.DB.Model(&audit.Event{}).Where("id = ?", events[1].ID).Update("ip", "10.0.0.1")
+	expectBroken(t, verify(t), events[1].ID, "Hash Doesn't Match The Event")
+}
+
+func TestChainEventRemoved(t *testing.T) {
+	_, events := chainedApp(t)
+	database.DB.Delete(&audit.Event{}, events[1].ID)
+	expectBroken(t, verify(t), events[2].ID, "Previous Hash")
+}
+
+func TestChainTruncated(t *testing.T) {
+	_, events := chainedApp(t)
+	database.DB.Delete(&audit.Event{}, events[3].ID)
+	expectBroken(t, verify(t), events[3].ID, "Chain Head")
+}
+
+// Rehashing Everything After An Edit Still Can't Match The Signed Checkpoint
+func TestChainRewritten(t *testing.T) {
+	_, events := chainedApp(t)
+	_, err := audit.CreateCheckpoint(database.DB)
+	if err != nil {
+		t.Fatalf("\nFailed To Checkpoint: %s\n", err.Error())
+	}
+
+	prev := events[0].Hash
+	for i := 1; i < len(events); i++ {
+		if i == 1 {
+			events[i].IP = "10.0.0.1"
+		}
+		events[i].PrevHash = prev
+		events[i].Hash, _ = events[i].ComputeHash()
+		database.DB.Model(&events[i]).Updates(map[string]interface{}{"ip": events[i].IP, "prev_hash": events[i].PrevHash, "hash": events[i].Hash})
+		prev = events[i].Hash
+	}
+	database.DB.Model(&audit.ChainHead{}).Where("1 = 1").Update("hash", prev)
+
+	expectBroken(t, verify(t), events[3].ID, "Checkpoint")
+}
+
+// An App With A Route That Records One Event Per Request
+func recordingApp(t *testing.T) *fiber.App {
+	app := testutil.Setup(t)
+	app.Post("/test/record", func(c *fiber.Ctx) error {
+		audit.Record(c, audit.Entry{Type: audit.SessionsRevoked})
+		return c.SendStatus(204)
+	})
+	return app
+}
+
+func record(t *testing.T, app *fiber.App) {
+	res, err := app.Test(httptest.NewRequest(http.MethodPost, "/test/record", nil), -1)
+	if err != nil || res.StatusCode != 204 {
+		t.Errorf("\nRecord Failed: %v %v\n", res, err)
+	}
+}
+
+/*
+Moves The Chain Head Just Before The Next *moves Appends Claim It, As Another
+Instance Appending At The Same Moment Would - Negative Means Every Append
+*/
+func moveChainHead(t *testing.T, moves *int) {
+	err := database.DB.Callback().Update().Before("gorm:update").Register("test:move_chain_head", func(db *gorm.DB) {
+		if db.Statement.Table != "audit_chain_heads" || *moves == 0 {
+			return
+		}
+		*moves--
+		db.Session(&gorm.Session{NewDB: true}).Exec("UPDATE audit_chain_heads SET hash = ?", strings.Repeat("f", 64))
+	})
+	if err != nil {
+		t.Fatalf("\nFailed To Register Callback: %s\n", err.Error())
+	}
+}
+
+func TestChainConcurrentAppends(t *testing.T) {
+	app := recordingApp(t)
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record(t, app)
+		}()
+	}
+	wg.Wait()
+
+	report := verify(t)
+	if !report.Valid || report.Events != n || report.Unchained != 0 {
+		t.Fatalf("\nUnexpected Report: %+v\n", report)
+	}
+}
+
+func TestChainHeadMoved(t *testing.T) {
+	app := recordingApp(t)
+	dropped := metrics.AuditEventsDropped.WithLabelValues(audit.SessionsRevoked)
+	before := promtest.ToFloat64(dropped)
+
+	// Losing The Race A Couple Of Times Still Lands The Event
+	record(t, app)
+	moves := 2
+	moveChainHead(t, &moves)
+	record(t, app)
+	report := verify(t)
+	if !report.Valid || report.Events != 2 {
+		t.Fatalf("\nUnexpected Report: %+v\n", report)
+	}
+
+	// Losing Every Time Drops It - Counted, And The Chain Stays Intact
+	moves = -1
+	record(t, app)
+	report = verify(t)
+	if !report.Valid || report.Events != 2 {
+		t.Fatalf("\nUnexpected Report: %+v\n", report)
+	}
+	if promtest.ToFloat64(dropped) != before+1 {
+		t.Fatalf("\nDropped Event Wasn't Counted\n")
+	}
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"app/api/auth"
+	"app/config"
+	"app/database"
+	"app/logging"
+)
+
+/*
+	Checkpoints
+*/
+
+/*
+The Head Of The Chain At Some Point, Signed With The Token Signing Key
+Rewriting The Chain Means Forging Every Later Checkpoint, Which Needs The Key
+Signature Is A JWT For The audit-checkpoint Audience - Verified Against
+The Key Ring, So Keep Retired Keys As Verification Keys To Check Old Ones
+*/
+type Checkpoint struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	EventID   uint      `json:"event_id" gorm:"not null;uniqueIndex"`
+	Hash      string    `json:"hash" gorm:"type:VARCHAR(64);not null"`
+	Signature string    `json:"signature" gorm:"type:TEXT;not null"`
+}
+
+func (Checkpoint) TableName() string {
+	return "audit_checkpoints"
+}
+
+type checkpointClaims struct {
+	jwt.RegisteredClaims
+	EventID uint   `json:"event_id"`
+	Hash    string `json:"hash"`
+}
+
+var (
+	errCheckpointSignature = errors.New("Checkpoint Signature Is Invalid")
+	errCheckpointClaims    = errors.New("Checkpoint Signature Is For Another Event")
+)
+
+// Signs The Head Of The Chain - Nil When Nothing Was Added Since The Last Checkpoint
+func CreateCheckpoint(db *gorm.DB) (*Checkpoint, error) {
+	var head ChainHead
+	err := db.Limit(1).Find(&head, chainHeadID).Error
+	if err != nil || head.EventID == 0 {
+		return nil, err
+	}
+
+	var last Checkpoint
+	err = db.Order("event_id DESC").Limit(1).Find(&last).Error
+	if err != nil || last.EventID == head.EventID {
+		return nil, err
+	}
+
+	keys, err := auth.Keys()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := keys.Sign(checkpointClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:   config.Get().Auth.Issuer,
+			Audience: jwt.ClaimStrings{auth.AudienceAuditCheckpoint},
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+		},
+		EventID: head.EventID,
+		Hash:    head.Hash,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := &Checkpoint{EventID: head.EventID, Hash: head.Hash, Signature: signature}
+	// Another Instance May Have Just Signed The Same Head
+	err = db.Clauses(clause.OnConflict{DoNothing: true}).Create(checkpoint).Error
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// Checks The Signature Covers This Checkpoint's Event And Hash
+func (cp *Checkpoint) Verify() error {
+	keys, err := auth.Keys()
+	if err != nil {
+		return err
+	}
+	claims := new(checkpointClaims)
+	_, err = jwt.ParseWithClaims(cp.Signature, claims, keys.Keyfunc, jwt.WithValidMethods(keys.Methods()))
+	if err != nil {
+		return errCheckpointSignature
+	}
+	if !claims.VerifyAudience(auth.AudienceAuditCheckpoint, true) || claims.EventID != cp.EventID || claims.Hash != cp.Hash {
+		return errCheckpointClaims
+	}
+	return nil
+}
+
+// Signs A Checkpoint Every audit.checkpoint_interval Until ctx Is Done
+func RunCheckpointer(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(config.Get().Audit.CheckpointInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		_, err := CreateCheckpoint(database.DB)
+		if err != nil {
+			logging.Get().Error("Audit Checkpoint Error", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"app/logging"
+)
+
+// A Field That Changed - From Is Nil For New Fields, To For Removed Ones
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Stands In For Secrets So The Log Shows They Changed Without What To
+const Redacted = logging.Redacted
+
+// Bookkeeping That Changes On Every Save
+var ignoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+/*
+Compares The JSON Form Of before And after Field By Field
+Structs Compare By Their json Tags, Nested Values As A Whole
+*/
+func Diff(before interface{}, after interface{}) (map[string]Change, error) {
+	from, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(from)+len(to))
+	for name := range from {
+		names = append(names, name)
+	}
+	for name := range to {
+		if _, ok := from[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := map[string]Change{}
+	for _, name := range names {
+		if ignoredFields[name] || reflect.DeepEqual(from[name], to[name]) {
+			continue
+		}
+		changes[name] = Change{From: redact(name, from[name]), To: redact(name, to[name])}
+	}
+	return changes, nil
+}
+
+// Flattens v Into Its Top Level JSON Fields - Nil Has None
+func fields(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return map[string]interface{}{}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+	return m, nil
+}
+
+// Empty Values Are Kept So Setting A Secret Still Reads Differently From Clearing It
+func redact(name string, value interface{}) interface{} {
+	if !logging.Secret(name) || value == nil || value == "" {
+		return value
+	}
+	return Redacted
+}
+
+func redactMap(m map[string]interface{}) map[string]interface{} {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for name, value := range m {
+		out[name] = redact(name, value)
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/database"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+/*
+Newest First, Filtered By Query Parameters - All Optional:
+actor_id, target_type, target_id, type (Comma Separated), since And until (RFC 3339),
+limit And before - Pass The Returned next As before For The Following Page
+*/
+func GetEvents(c *fiber.Ctx) error {
+	query := database.For(c).Model(&Event{})
+	invalid := func(param string) error {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Query Parameter: " + param})
+	}
+
+	for _, param := range []string{"actor_id", "target_id", "before"} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			return invalid(param)
+		}
+		switch param {
+		case "before":
+			query = query.Where("id < ?", id)
+		default:
+			query = query.Where(param+" = ?", id)
+		}
+	}
+	if target_type := c.Query("target_type"); target_type != "" {
+		query = query.Where("target_type = ?", target_type)
+	}
+	if types := c.Query("type"); types != "" {
+		query = query.Where("type IN ?", strings.Split(types, ","))
+	}
+	for _, param := range []string{"since", "until"} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		at, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return invalid(param)
+		}
+		if param == "since" {
+			query = query.Where("created_at >= ?", at)
+		} else {
+			query = query.Where("created_at < ?", at)
+		}
+	}
+
+	limit := c.QueryInt("limit", defaultPageSize)
+	if limit < 1 || limit > maxPageSize {
+		return invalid("limit")
+	}
+
+	// One Extra To Tell Whether There's Another Page
+	var events []Event
+	err := query.Order("id DESC").Limit(limit + 1).Find(&events).Error
+	if err != nil {
+		return c.SendStatus(500)
+	}
+
+	if len(events) > limit {
+		return c.Status(200).JSON(fiber.Map{"events": events[:limit], "next": events[limit-1].ID})
+	}
+	return c.Status(200).JSON(fiber.Map{"events": events})
+}
@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"app/database"
+)
+
+/*
+	Verification
+*/
+
+/*
+Outcome Of Walking The Chain - BrokenAt Is The First Event That Doesn't Check Out
+Unchained Events Were Recorded Before The Chain Began, So Can't Be Checked
+*/
+type Report struct {
+	Valid       bool  `json:"valid"`
+	Events      int64 `json:"events"`
+	Unchained   int64 `json:"unchained"`
+	Checkpoints int   `json:"checkpoints"`
+	// Event ID Of The Newest Checkpoint That Verified
+	LastCheckpoint uint   `json:"last_checkpoint,omitempty"`
+	BrokenAt       uint   `json:"broken_at,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+const verifyBatchSize = 500
+
+// Stops The Walk At The First Broken Link
+var errBrokenLink = errors.New("Broken Link")
+
+/*
+Recomputes Every Hash In Order, Checks Each Links To The One Before,
+That Each Checkpoint's Signature Holds And That Nothing Was Cut Off The End
+*/
+func Verify(db *gorm.DB) (*Report, error) {
+	var checkpoints []Checkpoint
+	err := db.Order("event_id").Find(&checkpoints).Error
+	if err != nil {
+		return nil, err
+	}
+	signed := make(map[uint]*Checkpoint, len(checkpoints))
+	for i := range checkpoints {
+		signed[checkpoints[i].EventID] = &checkpoints[i]
+	}
+
+	report := &Report{Checkpoints: len(checkpoints)}
+	broken := func(id uint, reason string) error {
+		report.BrokenAt = id
+		report.Reason = reason
+		return errBrokenLink
+	}
+
+	prev := ""
+	chained := false
+	var events []Event
+	err = db.FindInBatches(&events, verifyBatchSize, func(tx *gorm.DB, batch int) error {
+		for i := range events {
+			event := &events[i]
+			report.Events++
+
+			if event.Hash == "" {
+				if chained {
+					return broken(event.ID, "Event Isn't Chained")
+				}
+				report.Unchained++
+				continue
+			}
+			chained = true
+
+			if event.PrevHash != prev {
+				return broken(event.ID, "Previous Hash Doesn't Match The Event Before It")
+			}
+			hash, err := event.ComputeHash()
+			if err != nil {
+				return err
+			}
+			if hash != event.Hash {
+				return broken(event.ID, "Hash Doesn't Match The Event")
+			}
+			if checkpoint, ok := signed[event.ID]; ok {
+				if checkpoint.Hash != event.Hash {
+					return broken(event.ID, fmt.Sprintf("Hash Doesn't Match Checkpoint %d", checkpoint.ID))
+				}
+				err = checkpoint.Verify()
+				if err != nil {
+					return broken(event.ID, fmt.Sprintf("Checkpoint %d: %s", checkpoint.ID, err.Error()))
+				}
+				report.LastCheckpoint = event.ID
+				delete(signed, event.ID)
+			}
+			prev = event.Hash
+		}
+		return nil
+	}).Error
+	if errors.Is(err, errBrokenLink) {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Signed Events That Are No Longer There
+	if len(signed) != 0 {
+		missing := make([]uint, 0, len(signed))
+		for id := range signed {
+			missing = append(missing, id)
+		}
+		sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+		broken(missing[0], "Checkpointed Event Is Missing")
+		return report, nil
+	}
+
+	// Events Cut Off The End Since The Last Checkpoint
+	var head ChainHead
+	err = db.Limit(1).Find(&head, chainHeadID).Error
+	if err != nil {
+		return nil, err
+	}
+	if head.Hash != prev {
+		broken(head.EventID, "Chain Head Doesn't Match The Newest Event")
+		return report, nil
+	}
+
+	report.Valid = true
+	return report, nil
+}
+
+// Walks The Whole Chain - 200 Either Way, valid Says Whether It Held
+func VerifyChain(c *fiber.Ctx) error {
+	report, err := Verify(database.For(c))
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	return c.Status(200).JSON(fiber.Map{"report": report})
+}
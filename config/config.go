@@ -1,26 +1,616 @@
 package config
 
-var (
-	// API Settings
-	APP_PORT = 5000
-	MODE     = `DEV`
-	DEBUG    = true
-	// CORS Settings
-	ALLOW_ORIGINS = `*`
-	ALLOW_HEADERS = `*`
-	// Auth Settings
-	JWT_SECRET  = `Enter Your Secret`
-	JWT_EXPIRES = int64(84600) // One Day
-	SALT        = `SuperSALTYnotSweet`
-	// DB Settings
-	DB_USERNAME = `ryan`
-	DB_PASSWORD = `123`
-	DB_HOST     = `s.a`
-	DB_DATABASE = `test`
-	DB_PORT     = `3306`
-	// SMTP Settings
-	SMTP_ENABLED = false
-	SMTP_HOST    = ``
-	SMTP_USER    = ``
-	SMTP_PASS    = ``
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/goccy/go-json"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+Config Is Built In Three Layers - Each One Overrides The Last:
+ 1. Defaults (See Default)
+ 2. An Optional YAML, TOML Or JSON File
+ 3. APP_* Environment Variables (See The env Tags Below)
+*/
+type Config struct {
+	App    AppConfig    `json:"app" yaml:"app" toml:"app"`
+	Server ServerConfig `json:"server" yaml:"server" toml:"server"`
+	CORS   CORSConfig   `json:"cors" yaml:"cors" toml:"cors"`
+	Auth   AuthConfig   `json:"auth" yaml:"auth" toml:"auth"`
+	DB     DBConfig     `json:"db" yaml:"db" toml:"db"`
+	SMTP   SMTPConfig   `json:"smtp" yaml:"smtp" toml:"smtp"`
+	Mail   MailConfig   `json:"mail" yaml:"mail" toml:"mail"`
+	// Failed Login Tracking - See models/user/throttle.go
+	Lockout   LockoutConfig   `json:"lockout" yaml:"lockout" toml:"lockout"`
+	RateLimit RateLimitConfig `json:"rate_limit" yaml:"rate_limit" toml:"rate_limit"`
+	Audit     AuditConfig     `json:"audit" yaml:"audit" toml:"audit"`
+	Log       LogConfig       `json:"log" yaml:"log" toml:"log"`
+	Metrics   MetricsConfig   `json:"metrics" yaml:"metrics" toml:"metrics"`
+	Tracing   TracingConfig   `json:"tracing" yaml:"tracing" toml:"tracing"`
+}
+
+// API Settings
+type AppConfig struct {
+	Port int    `json:"port" yaml:"port" toml:"port" env:"APP_PORT"`
+	Mode string `json:"mode" yaml:"mode" toml:"mode" env:"APP_MODE"`
+	// Logs At debug Whatever log.level Says
+	Debug bool `json:"debug" yaml:"debug" toml:"debug" env:"APP_DEBUG"`
+	// Links In Emails Point Here
+	FrontendURL string `json:"frontend_url" yaml:"frontend_url" toml:"frontend_url" env:"APP_FRONTEND_URL"`
+}
+
+/*
+Behind A Load Balancer - Client Addresses Come From proxy_header, But Only
+On Requests From trusted_proxies. Rate Limits And Login Throttles Are Per
+Address, So Without This Every Client Shares The Proxy's
+*/
+type ServerConfig struct {
+	ProxyHeader string `json:"proxy_header" yaml:"proxy_header" toml:"proxy_header" env:"APP_SERVER_PROXY_HEADER"` // e.g. X-Forwarded-For
+	// IPs Or CIDR Ranges
+	TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies" toml:"trusted_proxies" env:"APP_SERVER_TRUSTED_PROXIES"`
+}
+
+// CORS Settings
+type CORSConfig struct {
+	AllowOrigins string `json:"allow_origins" yaml:"allow_origins" toml:"allow_origins" env:"APP_ALLOW_ORIGINS"`
+	AllowHeaders string `json:"allow_headers" yaml:"allow_headers" toml:"allow_headers" env:"APP_ALLOW_HEADERS"`
+}
+
+// Auth Settings
+type AuthConfig struct {
+	// HS256/384/512 Sign With jwt_secret, RS256, ES256/384/512 And EdDSA With signing_key_file
+	SigningAlg     string `json:"signing_alg" yaml:"signing_alg" toml:"signing_alg" env:"APP_JWT_SIGNING_ALG"`
+	SigningKeyFile string `json:"signing_key_file" yaml:"signing_key_file" toml:"signing_key_file" env:"APP_JWT_SIGNING_KEY_FILE"` // PEM Private Key
+	// PEM Keys Still Accepted For Verification While Rotating - Comma Separated In The Environment
+	VerificationKeyFiles []string `json:"verification_key_files" yaml:"verification_key_files" toml:"verification_key_files" env:"APP_JWT_VERIFICATION_KEY_FILES"`
+
+	// Stamped Into And Required Of Every Access Token
+	Issuer   string `json:"issuer" yaml:"issuer" toml:"issuer" env:"APP_JWT_ISSUER"`
+	Audience string `json:"audience" yaml:"audience" toml:"audience" env:"APP_JWT_AUDIENCE"`
+	Leeway   int64  `json:"leeway" yaml:"leeway" toml:"leeway" env:"APP_JWT_LEEWAY"` // Seconds Of Clock Skew Allowed
+
+	JWTSecret  string `json:"jwt_secret" yaml:"jwt_secret" toml:"jwt_secret" env:"APP_JWT_SECRET"`
+	JWTExpires int64  `json:"jwt_expires" yaml:"jwt_expires" toml:"jwt_expires" env:"APP_JWT_EXPIRES"` // Seconds
+	Salt       string `json:"salt" yaml:"salt" toml:"salt" env:"APP_SALT"`
+	// Seconds - Each Rotation Starts The Clock Again
+	RefreshTokenExpires int64 `json:"refresh_token_expires" yaml:"refresh_token_expires" toml:"refresh_token_expires" env:"APP_REFRESH_TOKEN_EXPIRES"`
+	// Seconds Another Instance's Logout Can Take To Be Seen Here
+	RevocationCacheTTL int64 `json:"revocation_cache_ttl" yaml:"revocation_cache_ttl" toml:"revocation_cache_ttl" env:"APP_REVOCATION_CACHE_TTL"`
+	// Seconds A Role Or Permission Change Can Take To Apply
+	PermissionCacheTTL int64 `json:"permission_cache_ttl" yaml:"permission_cache_ttl" toml:"permission_cache_ttl" env:"APP_PERMISSION_CACHE_TTL"`
+	// Seconds Personal Access Tokens Last When None Is Asked For, And The Longest Allowed
+	PersonalAccessTokenExpires    int64 `json:"personal_access_token_expires" yaml:"personal_access_token_expires" toml:"personal_access_token_expires" env:"APP_PERSONAL_ACCESS_TOKEN_EXPIRES"`
+	PersonalAccessTokenMaxExpires int64 `json:"personal_access_token_max_expires" yaml:"personal_access_token_max_expires" toml:"personal_access_token_max_expires" env:"APP_PERSONAL_ACCESS_TOKEN_MAX_EXPIRES"`
+	// Seconds Between Sweeps For Expired Role Grants
+	RoleGrantSweepInterval int64 `json:"role_grant_sweep_interval" yaml:"role_grant_sweep_interval" toml:"role_grant_sweep_interval" env:"APP_ROLE_GRANT_SWEEP_INTERVAL"`
+	// Seconds A Password Reset Link Stays Valid
+	PasswordResetExpires int64 `json:"password_reset_expires" yaml:"password_reset_expires" toml:"password_reset_expires" env:"APP_PASSWORD_RESET_EXPIRES"`
+	// Seconds An Email Verification Link Stays Valid
+	EmailVerificationExpires int64 `json:"email_verification_expires" yaml:"email_verification_expires" toml:"email_verification_expires" env:"APP_EMAIL_VERIFICATION_EXPIRES"`
+	// Seconds Between The Password And The Second Factor At Login
+	MFAChallengeExpires int64 `json:"mfa_challenge_expires" yaml:"mfa_challenge_expires" toml:"mfa_challenge_expires" env:"APP_MFA_CHALLENGE_EXPIRES"`
+	// Passkeys Are Bound To webauthn_rp_id (The Site's Domain) And Only Accepted From webauthn_origins
+	WebAuthnRPID    string   `json:"webauthn_rp_id" yaml:"webauthn_rp_id" toml:"webauthn_rp_id" env:"APP_WEBAUTHN_RP_ID"`
+	WebAuthnRPName  string   `json:"webauthn_rp_name" yaml:"webauthn_rp_name" toml:"webauthn_rp_name" env:"APP_WEBAUTHN_RP_NAME"`
+	WebAuthnOrigins []string `json:"webauthn_origins" yaml:"webauthn_origins" toml:"webauthn_origins" env:"APP_WEBAUTHN_ORIGINS"`
+	// Refuse Login Until The Account's Email Is Verified
+	RequireVerifiedEmail bool `json:"require_verified_email" yaml:"require_verified_email" toml:"require_verified_email" env:"APP_REQUIRE_VERIFIED_EMAIL"`
+}
+
+// DB Settings
+type DBConfig struct {
+	Driver   string `json:"driver" yaml:"driver" toml:"driver" env:"APP_DB_DRIVER"` // mysql, postgres or sqlite
+	DSN      string `json:"dsn" yaml:"dsn" toml:"dsn" env:"APP_DB_DSN"`             // Overrides Everything Below When Set
+	Username string `json:"username" yaml:"username" toml:"username" env:"APP_DB_USERNAME"`
+	Password string `json:"password" yaml:"password" toml:"password" env:"APP_DB_PASSWORD"`
+	Host     string `json:"host" yaml:"host" toml:"host" env:"APP_DB_HOST"`
+	Database string `json:"database" yaml:"database" toml:"database" env:"APP_DB_DATABASE"` // File Path For sqlite
+	Port     string `json:"port" yaml:"port" toml:"port" env:"APP_DB_PORT"`
+	SSLMode  string `json:"ssl_mode" yaml:"ssl_mode" toml:"ssl_mode" env:"APP_DB_SSL_MODE"` // postgres Only
+	// Apply Pending Migrations At Boot - Otherwise Boot Fails Until migrate up Is Run
+	MigrateOnStart bool `json:"migrate_on_start" yaml:"migrate_on_start" toml:"migrate_on_start" env:"APP_DB_MIGRATE_ON_START"`
+}
+
+// SMTP Settings
+type SMTPConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled" toml:"enabled" env:"APP_SMTP_ENABLED"`
+	Host    string `json:"host" yaml:"host" toml:"host" env:"APP_SMTP_HOST"` // host:port
+	User    string `json:"user" yaml:"user" toml:"user" env:"APP_SMTP_USER"`
+	Pass    string `json:"pass" yaml:"pass" toml:"pass" env:"APP_SMTP_PASS"`
+}
+
+// Outgoing Mail - Handlers Queue Messages, A Worker Delivers Them
+type MailConfig struct {
+	// smtp, file (Maildir Under dir) Or log - Empty Picks smtp When smtp.enabled Is Set, Otherwise log
+	Transport     string `json:"transport" yaml:"transport" toml:"transport" env:"APP_MAIL_TRANSPORT"`
+	Dir           string `json:"dir" yaml:"dir" toml:"dir" env:"APP_MAIL_DIR"`
+	From          string `json:"from" yaml:"from" toml:"from" env:"APP_MAIL_FROM"`
+	DefaultLocale string `json:"default_locale" yaml:"default_locale" toml:"default_locale" env:"APP_MAIL_DEFAULT_LOCALE"`
+	// Failed Sends Are Retried After retry_backoff Seconds, Doubling Each Attempt
+	MaxAttempts  int   `json:"max_attempts" yaml:"max_attempts" toml:"max_attempts" env:"APP_MAIL_MAX_ATTEMPTS"`
+	RetryBackoff int64 `json:"retry_backoff" yaml:"retry_backoff" toml:"retry_backoff" env:"APP_MAIL_RETRY_BACKOFF"`
+	PollInterval int64 `json:"poll_interval" yaml:"poll_interval" toml:"poll_interval" env:"APP_MAIL_POLL_INTERVAL"` // Seconds
+}
+
+/*
+Failed Logins Are Counted Per Account And Per IP Address
+After delay_after Failures Each Attempt Must Wait delay Seconds, Doubling
+Up To max_delay, And threshold Failures Lock The Account For duration
+*/
+type LockoutConfig struct {
+	Enabled     bool  `json:"enabled" yaml:"enabled" toml:"enabled" env:"APP_LOCKOUT_ENABLED"`
+	Window      int64 `json:"window" yaml:"window" toml:"window" env:"APP_LOCKOUT_WINDOW"` // Seconds Before Failures Are Forgotten
+	DelayAfter  int   `json:"delay_after" yaml:"delay_after" toml:"delay_after" env:"APP_LOCKOUT_DELAY_AFTER"`
+	Delay       int64 `json:"delay" yaml:"delay" toml:"delay" env:"APP_LOCKOUT_DELAY"`
+	MaxDelay    int64 `json:"max_delay" yaml:"max_delay" toml:"max_delay" env:"APP_LOCKOUT_MAX_DELAY"`
+	Threshold   int   `json:"threshold" yaml:"threshold" toml:"threshold" env:"APP_LOCKOUT_THRESHOLD"`
+	IPThreshold int   `json:"ip_threshold" yaml:"ip_threshold" toml:"ip_threshold" env:"APP_LOCKOUT_IP_THRESHOLD"`
+	Duration    int64 `json:"duration" yaml:"duration" toml:"duration" env:"APP_LOCKOUT_DURATION"`
+}
+
+/*
+Requests Allowed Per Window For Each Named Policy - Routes Pick A Policy
+By Name (See api/routes). Policies Can Only Be Changed In The Config File,
+Each One Given There Replaces The Default Of The Same Name
+*/
+type RateLimitConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled" toml:"enabled" env:"APP_RATE_LIMIT_ENABLED"`
+	// memory (Per Instance) Or sql (Shared Through The Database)
+	Store    string                     `json:"store" yaml:"store" toml:"store" env:"APP_RATE_LIMIT_STORE"`
+	Policies map[string]RateLimitPolicy `json:"policies" yaml:"policies" toml:"policies"`
+}
+
+type RateLimitPolicy struct {
+	Limit  int    `json:"limit" yaml:"limit" toml:"limit"`
+	Window int64  `json:"window" yaml:"window" toml:"window"` // Seconds
+	Key    string `json:"key" yaml:"key" toml:"key"`          // ip, user Or token
+}
+
+// Leveled Structured Logs - Format Defaults To json In PROD And text Otherwise
+type LogConfig struct {
+	Level  string `json:"level" yaml:"level" toml:"level" env:"APP_LOG_LEVEL"` // debug, info, warn Or error
+	Format string `json:"format" yaml:"format" toml:"format" env:"APP_LOG_FORMAT"`
+}
+
+/*
+Prometheus Scrape Endpoint - With A Token Set, Scrapers Must Send It As
+A Bearer Token. Leave It Empty Only When The Path Isn't Reachable From Outside
+*/
+type MetricsConfig struct {
+	Enabled bool   `json:"enabled" yaml:"enabled" toml:"enabled" env:"APP_METRICS_ENABLED"`
+	Path    string `json:"path" yaml:"path" toml:"path" env:"APP_METRICS_PATH"`
+	Token   string `json:"token" yaml:"token" toml:"token" env:"APP_METRICS_TOKEN"`
+}
+
+/*
+OpenTelemetry Spans - Sent To An OTLP/HTTP Collector At endpoint, Written
+To stdout, Or Kept In memory (Tests). sample_ratio Of New Traces Are Kept,
+Requests Arriving With A traceparent Follow The Caller's Decision
+*/
+type TracingConfig struct {
+	Enabled     bool    `json:"enabled" yaml:"enabled" toml:"enabled" env:"APP_TRACING_ENABLED"`
+	Exporter    string  `json:"exporter" yaml:"exporter" toml:"exporter" env:"APP_TRACING_EXPORTER"`
+	Endpoint    string  `json:"endpoint" yaml:"endpoint" toml:"endpoint" env:"APP_TRACING_ENDPOINT"` // e.g. http://localhost:4318
+	SampleRatio float64 `json:"sample_ratio" yaml:"sample_ratio" toml:"sample_ratio" env:"APP_TRACING_SAMPLE_RATIO"`
+	ServiceName string  `json:"service_name" yaml:"service_name" toml:"service_name" env:"APP_TRACING_SERVICE_NAME"`
+}
+
+const (
+	TracingOTLP   = `otlp`
+	TracingStdout = `stdout`
+	TracingMemory = `memory`
+)
+
+const (
+	LogJSON = `json`
+	LogText = `text`
+)
+
+// Format With The Empty Default Resolved
+func (log LogConfig) FormatFor(mode string) string {
+	if log.Format != "" {
+		return log.Format
+	}
+	if mode == ModeProd {
+		return LogJSON
+	}
+	return LogText
+}
+
+// Audit Log - Every checkpoint_interval Seconds The Head Of The Hash Chain Is Signed
+type AuditConfig struct {
+	CheckpointInterval int64 `json:"checkpoint_interval" yaml:"checkpoint_interval" toml:"checkpoint_interval" env:"APP_AUDIT_CHECKPOINT_INTERVAL"`
+}
+
+const (
+	RateLimitMemory = `memory`
+	RateLimitSQL    = `sql`
+
+	// Counted Per Client Address
+	RateLimitByIP = `ip`
+	// Per Signed In User - Falls Back To The Address
+	RateLimitByUser = `user`
+	// Per Personal Access Token, Then Per User, Then Per Address
+	RateLimitByToken = `token`
+)
+
+const (
+	MailSMTP = `smtp`
+	MailFile = `file`
+	MailLog  = `log`
 )
+
+// Transport With The Empty Default Resolved
+func (mail MailConfig) TransportFor(smtp SMTPConfig) string {
+	if mail.Transport != "" {
+		return mail.Transport
+	}
+	if smtp.Enabled {
+		return MailSMTP
+	}
+	return MailLog
+}
+
+// HMAC Algorithms Sign With jwt_secret Instead Of A Key File
+func (auth AuthConfig) UsesSharedSecret() bool {
+	switch auth.SigningAlg {
+	case "HS256", "HS384", "HS512":
+		return true
+	}
+	return false
+}
+
+const (
+	ModeDev  = `DEV`
+	ModeProd = `PROD`
+)
+
+const (
+	DriverMySQL    = `mysql`
+	DriverPostgres = `postgres`
+	DriverSQLite   = `sqlite`
+)
+
+// The Shipped Defaults - Fine For Local Development Only
+func Default() *Config {
+	return &Config{
+		App: AppConfig{
+			Port:  5000,
+			Mode:  ModeDev,
+			Debug: true,
+
+			FrontendURL: `http://localhost:3000`,
+		},
+		CORS: CORSConfig{
+			AllowOrigins: `*`,
+			AllowHeaders: `*`,
+		},
+		Auth: AuthConfig{
+			SigningAlg: `HS384`,
+			Issuer:     `user-auth`,
+			Audience:   `user-auth-api`,
+			Leeway:     30,
+			JWTSecret:  `Enter Your Secret`,
+			JWTExpires: 900, // 15 Minutes - Renewed With A Refresh Token
+			Salt:       `SuperSALTYnotSweet`,
+
+			RefreshTokenExpires: 2592000, // 30 Days
+			RevocationCacheTTL:  5,
+			PermissionCacheTTL:  5,
+
+			RoleGrantSweepInterval: 60,
+
+			PersonalAccessTokenExpires:    7776000,  // 90 Days
+			PersonalAccessTokenMaxExpires: 31536000, // One Year
+
+			PasswordResetExpires:     3600,  // One Hour
+			EmailVerificationExpires: 86400, // One Day
+			MFAChallengeExpires:      300,
+			RequireVerifiedEmail:     false,
+
+			WebAuthnRPID:    `localhost`,
+			WebAuthnRPName:  `User Auth`,
+			WebAuthnOrigins: []string{`http://localhost:3000`},
+		},
+		DB: DBConfig{
+			Driver:   DriverMySQL,
+			Username: `ryan`,
+			Password: `123`,
+			Host:     `s.a`,
+			Database: `test`,
+			Port:     `3306`,
+			SSLMode:  `disable`,
+
+			MigrateOnStart: true,
+		},
+		SMTP: SMTPConfig{
+			Enabled: false,
+		},
+		Mail: MailConfig{
+			Dir:           `maildir`,
+			From:          `no-reply@localhost`,
+			DefaultLocale: `en`,
+			MaxAttempts:   8,
+			RetryBackoff:  30, // 30s, 1m, 2m ... About An Hour In Total
+			PollInterval:  5,
+		},
+		Lockout: LockoutConfig{
+			Enabled:     true,
+			Window:      3600,
+			DelayAfter:  3,
+			Delay:       1, // 1s, 2s, 4s ...
+			MaxDelay:    60,
+			Threshold:   10,
+			IPThreshold: 50,
+			Duration:    900, // 15 Minutes
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Store:   RateLimitMemory,
+			Policies: map[string]RateLimitPolicy{
+				"login":         {Limit: 10, Window: 60, Key: RateLimitByIP},
+				"signup":        {Limit: 5, Window: 3600, Key: RateLimitByIP},
+				"email":         {Limit: 5, Window: 900, Key: RateLimitByIP},
+				"public":        {Limit: 60, Window: 60, Key: RateLimitByIP},
+				"authenticated": {Limit: 300, Window: 60, Key: RateLimitByToken},
+			},
+		},
+		Audit: AuditConfig{
+			CheckpointInterval: 3600,
+		},
+		Log: LogConfig{
+			Level: `info`,
+		},
+		Metrics: MetricsConfig{
+			Enabled: false, // Served On The API Port - Opt In
+			Path:    `/metrics`,
+		},
+		Tracing: TracingConfig{
+			Enabled:     false,
+			Exporter:    TracingStdout,
+			Endpoint:    `http://localhost:4318`,
+			SampleRatio: 1,
+			ServiceName: `user-auth`,
+		},
+	}
+}
+
+// Current Config - Defaults Until Set Is Called
+var current = Default()
+
+func Get() *Config {
+	return current
+}
+
+func Set(cfg *Config) {
+	current = cfg
+}
+
+/*
+Builds A Config From Defaults, Then The File At path (Skipped If Empty),
+Then The Environment - Returns Every Validation Error At Once
+*/
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		err := cfg.loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := cfg.loadEnv(os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Picks The Decoder From The File Extension
+func (cfg *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Unable To Read Config File: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	case ".json":
+		err = json.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("Unsupported Config File Type: %s", path)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Unable To Parse Config File %s: %w", path, err)
+	}
+	return nil
+}
+
+func (cfg *Config) Validate() error {
+	var errs []error
+
+	if cfg.App.Port < 1 || cfg.App.Port > 65535 {
+		errs = append(errs, fmt.Errorf("app.port: %d Is Not A Valid Port", cfg.App.Port))
+	}
+	if cfg.App.Mode != ModeDev && cfg.App.Mode != ModeProd {
+		errs = append(errs, fmt.Errorf("app.mode: Must Be %s or %s, Got %q", ModeDev, ModeProd, cfg.App.Mode))
+	}
+	if cfg.Server.ProxyHeader != "" && len(cfg.Server.TrustedProxies) == 0 {
+		errs = append(errs, errors.New("server.trusted_proxies: Required With server.proxy_header - Otherwise Any Client Can Pick Its Own Address"))
+	}
+	for _, proxy := range cfg.Server.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		if err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("server.trusted_proxies: %q Is Not An IP Or CIDR Range", proxy))
+		}
+	}
+	switch {
+	case cfg.Auth.UsesSharedSecret():
+		if cfg.Auth.JWTSecret == "" {
+			errs = append(errs, errors.New("auth.jwt_secret: Required"))
+		}
+	case cfg.Auth.SigningAlg == "RS256", strings.HasPrefix(cfg.Auth.SigningAlg, "ES"), cfg.Auth.SigningAlg == "EdDSA":
+		if cfg.Auth.SigningKeyFile == "" {
+			errs = append(errs, fmt.Errorf("auth.signing_key_file: Required For %s", cfg.Auth.SigningAlg))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("auth.signing_alg: Unsupported Algorithm %q", cfg.Auth.SigningAlg))
+	}
+	if cfg.Auth.Issuer == "" {
+		errs = append(errs, errors.New("auth.issuer: Required"))
+	}
+	if cfg.Auth.Audience == "" {
+		errs = append(errs, errors.New("auth.audience: Required"))
+	}
+	if cfg.Auth.Leeway < 0 {
+		errs = append(errs, errors.New("auth.leeway: Can't Be Negative"))
+	}
+	if cfg.Auth.JWTExpires <= 0 {
+		errs = append(errs, errors.New("auth.jwt_expires: Must Be Greater Than 0"))
+	}
+	if cfg.Auth.RefreshTokenExpires <= 0 {
+		errs = append(errs, errors.New("auth.refresh_token_expires: Must Be Greater Than 0"))
+	}
+	if cfg.Auth.RevocationCacheTTL < 0 {
+		errs = append(errs, errors.New("auth.revocation_cache_ttl: Can't Be Negative"))
+	}
+	if cfg.Auth.PermissionCacheTTL < 0 {
+		errs = append(errs, errors.New("auth.permission_cache_ttl: Can't Be Negative"))
+	}
+	if cfg.Auth.PersonalAccessTokenExpires <= 0 {
+		errs = append(errs, errors.New("auth.personal_access_token_expires: Must Be Greater Than 0"))
+	}
+	if cfg.Auth.PersonalAccessTokenMaxExpires < cfg.Auth.PersonalAccessTokenExpires {
+		errs = append(errs, errors.New("auth.personal_access_token_max_expires: Can't Be Less Than personal_access_token_expires"))
+	}
+	if cfg.Auth.RoleGrantSweepInterval <= 0 {
+		errs = append(errs, errors.New("auth.role_grant_sweep_interval: Must Be Greater Than 0"))
+	}
+	if cfg.Auth.Salt == "" {
+		errs = append(errs, errors.New("auth.salt: Required"))
+	}
+	switch cfg.DB.Driver {
+	case DriverMySQL, DriverPostgres:
+		if cfg.DB.Host == "" && cfg.DB.DSN == "" {
+			errs = append(errs, errors.New("db.host: Required"))
+		}
+	case DriverSQLite:
+	default:
+		errs = append(errs, fmt.Errorf("db.driver: Must Be %s, %s or %s, Got %q", DriverMySQL, DriverPostgres, DriverSQLite, cfg.DB.Driver))
+	}
+	if cfg.DB.Database == "" && cfg.DB.DSN == "" {
+		errs = append(errs, errors.New("db.database: Required"))
+	}
+	if cfg.Auth.PasswordResetExpires <= 0 {
+		errs = append(errs, errors.New("auth.password_reset_expires: Must Be Greater Than 0"))
+	}
+	if cfg.Auth.WebAuthnRPID == "" || cfg.Auth.WebAuthnRPName == "" || len(cfg.Auth.WebAuthnOrigins) == 0 {
+		errs = append(errs, errors.New("auth.webauthn_rp_id, auth.webauthn_rp_name, auth.webauthn_origins: Required"))
+	}
+	if cfg.Auth.MFAChallengeExpires <= 0 {
+		errs = append(errs, errors.New("auth.mfa_challenge_expires: Must Be Greater Than 0"))
+	}
+	if cfg.Auth.EmailVerificationExpires <= 0 {
+		errs = append(errs, errors.New("auth.email_verification_expires: Must Be Greater Than 0"))
+	}
+	switch cfg.Mail.TransportFor(cfg.SMTP) {
+	case MailSMTP:
+		if cfg.SMTP.Host == "" {
+			errs = append(errs, errors.New("smtp.host: Required When Sending Through SMTP"))
+		}
+	case MailFile:
+		if cfg.Mail.Dir == "" {
+			errs = append(errs, errors.New("mail.dir: Required For The file Transport"))
+		}
+	case MailLog:
+	default:
+		errs = append(errs, fmt.Errorf("mail.transport: Must Be %s, %s or %s, Got %q", MailSMTP, MailFile, MailLog, cfg.Mail.Transport))
+	}
+	if cfg.Mail.From == "" {
+		errs = append(errs, errors.New("mail.from: Required"))
+	}
+	if cfg.Mail.MaxAttempts < 1 {
+		errs = append(errs, errors.New("mail.max_attempts: Must Be At Least 1"))
+	}
+	if cfg.Mail.RetryBackoff <= 0 || cfg.Mail.PollInterval <= 0 {
+		errs = append(errs, errors.New("mail.retry_backoff, mail.poll_interval: Must Be Greater Than 0"))
+	}
+	switch cfg.RateLimit.Store {
+	case RateLimitMemory, RateLimitSQL:
+	default:
+		errs = append(errs, fmt.Errorf("rate_limit.store: Must Be %s or %s, Got %q", RateLimitMemory, RateLimitSQL, cfg.RateLimit.Store))
+	}
+	for name, policy := range cfg.RateLimit.Policies {
+		if policy.Limit < 1 || policy.Window <= 0 {
+			errs = append(errs, fmt.Errorf("rate_limit.policies.%s: limit And window Must Be Greater Than 0", name))
+		}
+		switch policy.Key {
+		case RateLimitByIP, RateLimitByUser, RateLimitByToken:
+		default:
+			errs = append(errs, fmt.Errorf("rate_limit.policies.%s.key: Must Be %s, %s or %s, Got %q", name, RateLimitByIP, RateLimitByUser, RateLimitByToken, policy.Key))
+		}
+	}
+	if cfg.Lockout.Enabled {
+		if cfg.Lockout.Window <= 0 || cfg.Lockout.Delay <= 0 || cfg.Lockout.Duration <= 0 {
+			errs = append(errs, errors.New("lockout.window, lockout.delay, lockout.duration: Must Be Greater Than 0"))
+		}
+		if cfg.Lockout.MaxDelay < cfg.Lockout.Delay {
+			errs = append(errs, errors.New("lockout.max_delay: Can't Be Less Than lockout.delay"))
+		}
+		if cfg.Lockout.DelayAfter < 1 || cfg.Lockout.Threshold <= cfg.Lockout.DelayAfter || cfg.Lockout.IPThreshold <= cfg.Lockout.DelayAfter {
+			errs = append(errs, errors.New("lockout.threshold, lockout.ip_threshold: Must Be Greater Than lockout.delay_after, Which Must Be At Least 1"))
+		}
+	}
+	switch cfg.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level: Must Be debug, info, warn or error, Got %q", cfg.Log.Level))
+	}
+	switch cfg.Log.FormatFor(cfg.App.Mode) {
+	case LogJSON, LogText:
+	default:
+		errs = append(errs, fmt.Errorf("log.format: Must Be %s or %s, Got %q", LogJSON, LogText, cfg.Log.Format))
+	}
+	if cfg.Audit.CheckpointInterval <= 0 {
+		errs = append(errs, errors.New("audit.checkpoint_interval: Must Be Greater Than 0"))
+	}
+	if cfg.Metrics.Enabled && !strings.HasPrefix(cfg.Metrics.Path, "/") {
+		errs = append(errs, fmt.Errorf("metrics.path: Must Start With /, Got %q", cfg.Metrics.Path))
+	}
+	if cfg.Tracing.Enabled {
+		switch cfg.Tracing.Exporter {
+		case TracingOTLP:
+			if cfg.Tracing.Endpoint == "" {
+				errs = append(errs, errors.New("tracing.endpoint: Required For The otlp Exporter"))
+			}
+		case TracingStdout, TracingMemory:
+		default:
+			errs = append(errs, fmt.Errorf("tracing.exporter: Must Be %s, %s or %s, Got %q", TracingOTLP, TracingStdout, TracingMemory, cfg.Tracing.Exporter))
+		}
+		if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+			errs = append(errs, fmt.Errorf("tracing.sample_ratio: Must Be Between 0 And 1, Got %v", cfg.Tracing.SampleRatio))
+		}
+		if cfg.Tracing.ServiceName == "" {
+			errs = append(errs, errors.New("tracing.service_name: Required"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
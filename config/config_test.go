@@ -0,0 +1,171 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name string, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(contents), 0600)
+	if err != nil {
+		t.Fatalf("\nFailed To Write %s: %s\n", name, err.Error())
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("\nDefaults Failed Validation: %s\n", err.Error())
+	}
+	if cfg.App.Port != 5000 {
+		t.Fatalf("\nInvalid Port: %d Expected: %d\n", cfg.App.Port, 5000)
+	}
+}
+
+// File Overrides Defaults, Environment Overrides File
+func TestLoadPrecedence(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "app:\n  port: 6000\n  debug: false\ndb:\n  host: db.yaml\n",
+		"config.toml": "[app]\nport = 6000\ndebug = false\n[db]\nhost = \"db.toml\"\n",
+		"config.json": `{"app": {"port": 6000, "debug": false}, "db": {"host": "db.json"}}`,
+	}
+
+	for name, contents := range files {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("APP_PORT", "7000")
+			path := writeFile(t, name, contents)
+
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatalf("\nFailed To Load: %s\n", err.Error())
+			}
+			if cfg.App.Port != 7000 {
+				t.Fatalf("\nInvalid Port: %d Expected: %d\n", cfg.App.Port, 7000)
+			}
+			if cfg.App.Debug {
+				t.Fatalf("\nExpected Debug To Be Disabled By File\n")
+			}
+			expectedHost := "db" + filepath.Ext(name)
+			if cfg.DB.Host != expectedHost {
+				t.Fatalf("\nInvalid DB Host: %s Expected: %s\n", cfg.DB.Host, expectedHost)
+			}
+			// Untouched Values Keep Their Defaults
+			if cfg.Auth.JWTExpires != Default().Auth.JWTExpires {
+				t.Fatalf("\nExpected Default JWT Expiry, Got: %d\n", cfg.Auth.JWTExpires)
+			}
+		})
+	}
+}
+
+func TestLoadReportsEveryError(t *testing.T) {
+	t.Setenv("APP_PORT", "0")
+	t.Setenv("APP_MODE", "STAGING")
+	t.Setenv("APP_JWT_SECRET", "")
+
+	_, err := Load("")
+	if err == nil {
+		t.Fatalf("\nExpected Validation Errors\n")
+	}
+	for _, field := range []string{"app.port", "app.mode", "auth.jwt_secret"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Fatalf("\nMissing Error For %s In: %s\n", field, err.Error())
+		}
+	}
+}
+
+func TestLoadInvalidEnv(t *testing.T) {
+	t.Setenv("APP_DEBUG", "sometimes")
+
+	_, err := Load("")
+	if err == nil || !strings.Contains(err.Error(), "APP_DEBUG") {
+		t.Fatalf("\nExpected APP_DEBUG Error, Got: %v\n", err)
+	}
+}
+
+func TestLoadTracingEnv(t *testing.T) {
+	t.Setenv("APP_TRACING_ENABLED", "true")
+	t.Setenv("APP_TRACING_SAMPLE_RATIO", "0.25")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("\nFailed To Load: %s\n", err.Error())
+	}
+	if !cfg.Tracing.Enabled || cfg.Tracing.SampleRatio != 0.25 {
+		t.Fatalf("\nUnexpected Tracing Config: %+v\n", cfg.Tracing)
+	}
+
+	t.Setenv("APP_TRACING_SAMPLE_RATIO", "2")
+	_, err = Load("")
+	if err == nil || !strings.Contains(err.Error(), "tracing.sample_ratio") {
+		t.Fatalf("\nExpected tracing.sample_ratio Error, Got: %v\n", err)
+	}
+}
+
+func TestLoadServerEnv(t *testing.T) {
+	t.Setenv("APP_SERVER_PROXY_HEADER", "X-Forwarded-For")
+	t.Setenv("APP_SERVER_TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.5")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("\nFailed To Load: %s\n", err.Error())
+	}
+	if len(cfg.Server.TrustedProxies) != 2 || cfg.Server.TrustedProxies[1] != "192.168.1.5" {
+		t.Fatalf("\nUnexpected Server Config: %+v\n", cfg.Server)
+	}
+
+	// A Header Anyone Can Set Or A Proxy That Isn't An Address
+	for _, proxies := range []string{"", "10.0.0.0/8,load-balancer"} {
+		t.Setenv("APP_SERVER_TRUSTED_PROXIES", proxies)
+		_, err = Load("")
+		if err == nil || !strings.Contains(err.Error(), "server.trusted_proxies") {
+			t.Fatalf("\nExpected server.trusted_proxies Error For %q, Got: %v\n", proxies, err)
+		}
+	}
+}
+
+func TestSafetyCheckIgnoresDev(t *testing.T) {
+	err := Default().SafetyCheck()
+	if err != nil {
+		t.Fatalf("\nDEV Mode Should Never Fail The Safety Check: %s\n", err.Error())
+	}
+}
+
+func TestSafetyCheckReportsEveryViolation(t *testing.T) {
+	cfg := Default()
+	cfg.App.Mode = ModeProd
+	cfg.Metrics.Enabled = true
+
+	err := cfg.SafetyCheck()
+	if err == nil {
+		t.Fatalf("\nExpected PROD Mode With Defaults To Fail\n")
+	}
+	for _, setting := range []string{"auth.jwt_secret", "auth.salt", "cors.allow_origins", "app.debug", "metrics.token"} {
+		if !strings.Contains(err.Error(), setting) {
+			t.Fatalf("\nMissing Violation For %s In: %s\n", setting, err.Error())
+		}
+	}
+}
+
+func TestSafetyCheckEntropy(t *testing.T) {
+	cfg := Default()
+	cfg.App.Mode = ModeProd
+	cfg.App.Debug = false
+	cfg.CORS.AllowOrigins = "https://example.com"
+	cfg.Auth.Salt = "a-different-salt"
+
+	cfg.Auth.JWTSecret = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	err := cfg.SafetyCheck()
+	if err == nil || !strings.Contains(err.Error(), "Entropy") {
+		t.Fatalf("\nExpected A Low Entropy Violation, Got: %v\n", err)
+	}
+
+	cfg.Auth.JWTSecret = "q7Vw2pLx9Zr4Tn8Ks1Bd6Hf3Jm5Yc0Ge-Ua_RoWiXtQlPvNsEk"
+	err = cfg.SafetyCheck()
+	if err != nil {
+		t.Fatalf("\nExpected A Strong Secret To Pass: %s\n", err.Error())
+	}
+}
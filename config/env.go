@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Matches os.LookupEnv - Swappable For Tests
+type lookupFunc func(key string) (string, bool)
+
+// Walks The Config And Overrides Every Field That Has An env Tag And A Set Variable
+func (cfg *Config) loadEnv(lookup lookupFunc) error {
+	return errors.Join(setFromEnv(reflect.ValueOf(cfg).Elem(), lookup)...)
+}
+
+func setFromEnv(v reflect.Value, lookup lookupFunc) []error {
+	var errs []error
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+
+		// Recurse Into Sections
+		if field.Kind() == reflect.Struct {
+			errs = append(errs, setFromEnv(field, lookup)...)
+			continue
+		}
+
+		key := t.Field(i).Tag.Get("env")
+		if key == "" {
+			continue
+		}
+		value, ok := lookup(key)
+		if !ok {
+			continue
+		}
+
+		err := setField(field, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return errs
+}
+
+func setField(field reflect.Value, value string) error {
+	value = strings.TrimSpace(value)
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("Invalid Boolean %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid Integer %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid Number %q", value)
+		}
+		field.SetFloat(n)
+	case reflect.Slice:
+		// Comma Separated Lists
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("Unsupported Slice Type %s", field.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("Unsupported Field Type %s", field.Type())
+	}
+	return nil
+}
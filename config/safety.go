@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Shannon Estimate Of The Whole Secret - 32 Random Base64 Characters Clears It Easily
+const MinSecretEntropyBits = 128
+
+/*
+Refuses Insecure Settings In PROD Mode - Every Violation Is Collected
+So Ops Can Fix Them All In One Pass. Always Passes In DEV Mode.
+*/
+func (cfg *Config) SafetyCheck() error {
+	if cfg.App.Mode != ModeProd {
+		return nil
+	}
+
+	defaults := Default()
+	var violations []string
+
+	// The Secret Only Matters When It Signs Tokens
+	if cfg.Auth.UsesSharedSecret() {
+		if cfg.Auth.JWTSecret == defaults.Auth.JWTSecret {
+			violations = append(violations, "auth.jwt_secret (APP_JWT_SECRET) Is Still The Shipped Default")
+		} else if bits := EntropyBits(cfg.Auth.JWTSecret); bits < MinSecretEntropyBits {
+			violations = append(violations, fmt.Sprintf("auth.jwt_secret (APP_JWT_SECRET) Has ~%.0f Bits Of Entropy, Need At Least %d", bits, MinSecretEntropyBits))
+		}
+	}
+	if cfg.Auth.Salt == defaults.Auth.Salt {
+		violations = append(violations, "auth.salt (APP_SALT) Is Still The Shipped Default")
+	}
+	for _, origin := range strings.Split(cfg.CORS.AllowOrigins, ",") {
+		if strings.TrimSpace(origin) == "*" {
+			violations = append(violations, "cors.allow_origins (APP_ALLOW_ORIGINS) Must List Origins Instead Of *")
+			break
+		}
+	}
+	if cfg.App.Debug {
+		violations = append(violations, "app.debug (APP_DEBUG) Must Be false")
+	}
+	// Login Failures, Lockouts And Token Counts Aren't For The Public
+	if cfg.Metrics.Enabled && cfg.Metrics.Token == "" {
+		violations = append(violations, "metrics.token (APP_METRICS_TOKEN) Is Required With metrics.enabled")
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("Refusing To Start In %s Mode With %d Insecure Setting(s):\n  - %s",
+		ModeProd, len(violations), strings.Join(violations, "\n  - "))
+}
+
+// Estimates Total Entropy As Per-Character Shannon Entropy Times Length
+func EntropyBits(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	perChar := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(total)
+		perChar -= p * math.Log2(p)
+	}
+	return perChar * float64(total)
+}
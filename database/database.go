@@ -2,10 +2,17 @@ package database
 
 import (
 	"app/config"
+	"app/logging"
 	"fmt"
 	"log"
+	"net"
+	"net/url"
 
+	"github.com/glebarez/sqlite"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/gofiber/fiber/v2"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
@@ -13,26 +20,73 @@ var (
 	DB *gorm.DB
 )
 
-func InitDB() {
+func InitDB(cfg *config.Config) {
 
 	var err error
-	// DB, err = gorm.Connect("mysql", GenerateDBURL())
-	DB, err = gorm.Open(mysql.Open(GenerateDBURL()), &gorm.Config{})
+	DB, err = Open(cfg.DB)
 
 	if err != nil {
-		log.Fatalf(`\nFailed To Connect To Database: %v+\n`, err)
+		log.Fatalf("\nFailed To Connect To Database: %v\n", err)
 	}
 
-	fmt.Println("\nSuccessfully Connected To Database")
-	// seed.Seed()
+	logging.Get().Info("Successfully Connected To Database", "driver", cfg.DB.Driver)
 }
 
-func GenerateDBURL() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True",
-		config.DB_USERNAME,
-		config.DB_PASSWORD,
-		config.DB_HOST,
-		config.DB_PORT,
-		config.DB_DATABASE,
-	)
+// Opens A Connection With The Configured Driver Without Touching DB
+func Open(db config.DBConfig) (*gorm.DB, error) {
+	dialector, err := Dialector(db)
+	if err != nil {
+		return nil, err
+	}
+	return gorm.Open(dialector, &gorm.Config{})
+}
+
+func Dialector(db config.DBConfig) (gorm.Dialector, error) {
+	dsn := db.DSN
+	if dsn == "" {
+		dsn = GenerateDBURL(db)
+	}
+
+	switch db.Driver {
+	case config.DriverMySQL:
+		return mysql.Open(dsn), nil
+	case config.DriverPostgres:
+		return postgres.Open(dsn), nil
+	case config.DriverSQLite:
+		return sqlite.Open(dsn), nil
+	}
+	return nil, fmt.Errorf("Unsupported Database Driver: %q", db.Driver)
+}
+
+// DB Bound To The Request's Context So Its Statements Join The Request's Trace
+func For(c *fiber.Ctx) *gorm.DB {
+	return DB.WithContext(c.UserContext())
+}
+
+// Builds The DSN For The Configured Driver
+func GenerateDBURL(db config.DBConfig) string {
+	switch db.Driver {
+	case config.DriverPostgres:
+		u := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(db.Username, db.Password),
+			Host:     db.Host + ":" + db.Port,
+			Path:     "/" + db.Database,
+			RawQuery: "sslmode=" + url.QueryEscape(db.SSLMode),
+		}
+		return u.String()
+	case config.DriverSQLite:
+		// Foreign Keys Are Off By Default In SQLite
+		return fmt.Sprintf("%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", db.Database)
+	}
+	// Left To The Driver So Odd Characters In The Password Survive
+	dsn := mysqldriver.NewConfig()
+	dsn.User = db.Username
+	dsn.Passwd = db.Password
+	dsn.Net = "tcp"
+	dsn.Addr = net.JoinHostPort(db.Host, db.Port)
+	dsn.DBName = db.Database
+	dsn.ParseTime = true
+	dsn.Params = map[string]string{"charset": "utf8"}
+	return dsn.FormatDSN()
 }
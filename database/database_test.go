@@ -0,0 +1,135 @@
+package database_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"app/config"
+	"app/database"
+)
+
+// Passwords Real Deployments Have Tripped Over
+var passwords = []string{
+	"plain",
+	"p@ss:w/rd",
+	"?#&=%+ ;",
+	"ünïcødé'\"`",
+}
+
+func TestGenerateDBURLPostgres(t *testing.T) {
+	for _, password := range passwords {
+		t.Run(password, func(t *testing.T) {
+			db := config.DBConfig{
+				Driver:   config.DriverPostgres,
+				Username: "app user",
+				Password: password,
+				Host:     "db.internal",
+				Port:     "5433",
+				Database: "users",
+				SSLMode:  "verify-full",
+			}
+			parsed, err := pgconn.ParseConfig(database.GenerateDBURL(db))
+			if err != nil {
+				t.Fatalf("\nDSN Didn't Parse: %s\n", err.Error())
+			}
+			if parsed.User != db.Username || parsed.Password != password || parsed.Host != db.Host || parsed.Port != 5433 || parsed.Database != db.Database {
+				t.Fatalf("\nUnexpected Connection: %+v\n", parsed)
+			}
+			if parsed.TLSConfig == nil || parsed.TLSConfig.ServerName != db.Host {
+				t.Fatalf("\nssl_mode Wasn't Applied\n")
+			}
+		})
+	}
+}
+
+func TestGenerateDBURLMySQL(t *testing.T) {
+	for _, password := range passwords {
+		t.Run(password, func(t *testing.T) {
+			db := config.DBConfig{
+				Driver:   config.DriverMySQL,
+				Username: "app",
+				Password: password,
+				Host:     "db.internal",
+				Port:     "3307",
+				Database: "users",
+			}
+			parsed, err := mysqldriver.ParseDSN(database.GenerateDBURL(db))
+			if err != nil {
+				t.Fatalf("\nDSN Didn't Parse: %s\n", err.Error())
+			}
+			if parsed.User != db.Username || parsed.Passwd != password || parsed.Net != "tcp" || parsed.Addr != "db.internal:3307" || parsed.DBName != db.Database {
+				t.Fatalf("\nUnexpected Connection: %+v\n", parsed)
+			}
+			if !parsed.ParseTime || parsed.Params["charset"] != "utf8" {
+				t.Fatalf("\nMissing Options: %+v\n", parsed)
+			}
+		})
+	}
+}
+
+func TestGenerateDBURLSQLite(t *testing.T) {
+	tests := []struct {
+		database string
+		expected string
+	}{
+		{"app.db", "app.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"},
+		{"/var/lib/app/users.db", "/var/lib/app/users.db?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"},
+		{":memory:", ":memory:?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"},
+	}
+	for _, test := range tests {
+		dsn := database.GenerateDBURL(config.DBConfig{Driver: config.DriverSQLite, Database: test.database})
+		if dsn != test.expected {
+			t.Fatalf("\nUnexpected DSN: %s Expected: %s\n", dsn, test.expected)
+		}
+	}
+
+	// Pragmas Take Effect And Paths With Spaces Open
+	db, err := database.Open(config.DBConfig{Driver: config.DriverSQLite, Database: filepath.Join(t.TempDir(), "my app.db")})
+	if err != nil {
+		t.Fatalf("\nFailed To Open: %s\n", err.Error())
+	}
+	var foreignKeys int
+	db.Raw("PRAGMA foreign_keys").Scan(&foreignKeys)
+	if foreignKeys != 1 {
+		t.Fatalf("\nForeign Keys Are Off\n")
+	}
+}
+
+func TestDialector(t *testing.T) {
+	tests := []struct {
+		db       config.DBConfig
+		expected string
+	}{
+		{config.DBConfig{Driver: config.DriverMySQL, Host: "localhost", Port: "3306"}, "mysql"},
+		{config.DBConfig{Driver: config.DriverPostgres, Host: "localhost", Port: "5432"}, "postgres"},
+		{config.DBConfig{Driver: config.DriverSQLite, Database: "app.db"}, "sqlite"},
+		// DSN Wins Over The Separate Fields
+		{config.DBConfig{Driver: config.DriverSQLite, DSN: "file::memory:", Database: "ignored.db"}, "sqlite"},
+	}
+	for _, test := range tests {
+		dialector, err := database.Dialector(test.db)
+		if err != nil {
+			t.Fatalf("\nUnexpected Error For %s: %s\n", test.db.Driver, err.Error())
+		}
+		if dialector.Name() != test.expected {
+			t.Fatalf("\nUnexpected Dialector: %s Expected: %s\n", dialector.Name(), test.expected)
+		}
+	}
+
+	db, err := database.Open(config.DBConfig{Driver: config.DriverSQLite, DSN: "file::memory:", Database: filepath.Join(t.TempDir(), "missing", "app.db")})
+	if err != nil {
+		t.Fatalf("\nDSN Wasn't Used: %s\n", err.Error())
+	}
+	var one int
+	if db.Raw("SELECT 1").Scan(&one).Error != nil || one != 1 {
+		t.Fatalf("\nIn-Memory Database Unusable\n")
+	}
+
+	_, err = database.Dialector(config.DBConfig{Driver: "oracle"})
+	if err == nil {
+		t.Fatalf("\nUnknown Driver Accepted\n")
+	}
+}
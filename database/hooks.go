@@ -0,0 +1,34 @@
+package database
+
+import "gorm.io/gorm"
+
+/*
+Registers before And after Around Every Kind Of Statement GORM Runs - Each
+Is Built For Its Operation (create, query...) And Named name:before_<operation>
+*/
+func Hook(db *gorm.DB, name string, before func(operation string) func(*gorm.DB), after func(operation string) func(*gorm.DB)) error {
+	callbacks := db.Callback()
+	hooks := []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+	for _, hook := range hooks {
+		err := hook.before(name+":before_"+hook.operation, before(hook.operation))
+		if err != nil {
+			return err
+		}
+		err = hook.after(name+":after_"+hook.operation, after(hook.operation))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
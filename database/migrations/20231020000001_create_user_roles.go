@@ -0,0 +1,25 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	Register(&Migration{
+		Version: "20231020000001",
+		Name:    "create_user_roles",
+		Up: func(tx *gorm.DB) error {
+			type UserRole struct {
+				gorm.Model
+				Role        string `gorm:"type:VARCHAR(32);unique;not null"`
+				Description string `gorm:"type:VARCHAR(100);"`
+			}
+			// Databases Created By The Old AutoMigrate Already Have It
+			if tx.Migrator().HasTable(&UserRole{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&UserRole{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("user_roles")
+		},
+	})
+}
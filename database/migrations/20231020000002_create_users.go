@@ -0,0 +1,33 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	Register(&Migration{
+		Version: "20231020000002",
+		Name:    "create_users",
+		Up: func(tx *gorm.DB) error {
+			type UserRole struct {
+				gorm.Model
+			}
+			type User struct {
+				gorm.Model
+				Username       string `gorm:"type:VARCHAR(16);not null;uniqueIndex:idx_username;"`
+				Password       string `gorm:"type:VARCHAR(64);not null"`
+				Email          string `gorm:"type:VARCHAR(48);not null;uniqueIndex:idx_email"`
+				Phone          string `gorm:"type:VARCHAR(13)"`
+				AccountEnabled *bool  `gorm:"default:true;not null"`
+				RoleID         uint
+				Role           UserRole
+			}
+			// Databases Created By The Old AutoMigrate Already Have It
+			if tx.Migrator().HasTable(&User{}) {
+				return nil
+			}
+			return tx.Migrator().CreateTable(&User{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("users")
+		},
+	})
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20231101000001",
+		Name:    "create_refresh_tokens",
+		Up: func(tx *gorm.DB) error {
+			type RefreshToken struct {
+				ID        uint `gorm:"primaryKey"`
+				CreatedAt time.Time
+				UserID    uint      `gorm:"not null;index"`
+				FamilyID  string    `gorm:"type:VARCHAR(64);not null;index"`
+				TokenHash string    `gorm:"type:VARCHAR(64);not null;uniqueIndex"`
+				ExpiresAt time.Time `gorm:"not null"`
+				UsedAt    *time.Time
+				RevokedAt *time.Time
+			}
+			return tx.Migrator().CreateTable(&RefreshToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("refresh_tokens")
+		},
+	})
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20231105000001",
+		Name:    "create_token_revocations",
+		Up: func(tx *gorm.DB) error {
+			type RevokedToken struct {
+				JTI       string    `gorm:"type:VARCHAR(64);primaryKey"`
+				UserID    uint      `gorm:"not null;index"`
+				ExpiresAt time.Time `gorm:"not null;index"`
+				CreatedAt time.Time
+			}
+			type UserTokenRevocation struct {
+				UserID        uint      `gorm:"primaryKey;autoIncrement:false"`
+				RevokedBefore time.Time `gorm:"not null"`
+			}
+			return tx.Migrator().CreateTable(&RevokedToken{}, &UserTokenRevocation{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("user_token_revocations", "revoked_tokens")
+		},
+	})
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	Register(&Migration{
+		Version: "20231110000001",
+		Name:    "add_token_generation",
+		Up: func(tx *gorm.DB) error {
+			type UserTokenRevocation struct {
+				Generation int64 `gorm:"not null;default:0"`
+			}
+			return tx.Migrator().AddColumn(&UserTokenRevocation{}, "Generation")
+		},
+		Down: func(tx *gorm.DB) error {
+			type UserTokenRevocation struct {
+				Generation int64
+			}
+			return tx.Migrator().DropColumn(&UserTokenRevocation{}, "Generation")
+		},
+	})
+}
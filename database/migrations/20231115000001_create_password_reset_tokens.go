@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20231115000001",
+		Name:    "create_password_reset_tokens",
+		Up: func(tx *gorm.DB) error {
+			type PasswordResetToken struct {
+				ID        uint `gorm:"primaryKey"`
+				CreatedAt time.Time
+				UserID    uint      `gorm:"not null;index"`
+				TokenHash string    `gorm:"type:VARCHAR(64);not null;uniqueIndex"`
+				ExpiresAt time.Time `gorm:"not null"`
+				UsedAt    *time.Time
+			}
+			return tx.Migrator().CreateTable(&PasswordResetToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("password_reset_tokens")
+		},
+	})
+}
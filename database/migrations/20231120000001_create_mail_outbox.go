@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20231120000001",
+		Name:    "create_mail_outbox",
+		Up: func(tx *gorm.DB) error {
+			type OutboxMessage struct {
+				ID            uint `gorm:"primaryKey"`
+				CreatedAt     time.Time
+				UpdatedAt     time.Time
+				Recipient     string    `gorm:"type:VARCHAR(254);not null"`
+				Subject       string    `gorm:"type:VARCHAR(255);not null"`
+				Text          string    `gorm:"type:TEXT"`
+				HTML          string    `gorm:"type:TEXT"`
+				Attempts      int       `gorm:"not null;default:0"`
+				NextAttemptAt time.Time `gorm:"not null;index:idx_mail_outbox_next_attempt_at"`
+				LockedUntil   *time.Time
+				SentAt        *time.Time
+				FailedAt      *time.Time
+				LastError     string `gorm:"type:VARCHAR(1024)"`
+			}
+			return tx.Table("mail_outbox").Migrator().CreateTable(&OutboxMessage{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("mail_outbox")
+		},
+	})
+}
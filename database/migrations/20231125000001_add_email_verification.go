@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20231125000001",
+		Name:    "add_email_verification",
+		Up: func(tx *gorm.DB) error {
+			type User struct {
+				EmailVerifiedAt *time.Time
+				PendingEmail    string `gorm:"type:VARCHAR(48)"`
+			}
+			err := tx.Migrator().AddColumn(&User{}, "EmailVerifiedAt")
+			if err != nil {
+				return err
+			}
+			err = tx.Migrator().AddColumn(&User{}, "PendingEmail")
+			if err != nil {
+				return err
+			}
+			// Accounts From Before Verification Existed Count As Verified
+			return tx.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			type User struct {
+				EmailVerifiedAt *time.Time
+				PendingEmail    string
+			}
+			err := tx.Migrator().DropColumn(&User{}, "PendingEmail")
+			if err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&User{}, "EmailVerifiedAt")
+		},
+	})
+}
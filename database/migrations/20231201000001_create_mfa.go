@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20231201000001",
+		Name:    "create_mfa",
+		Up: func(tx *gorm.DB) error {
+			type TOTPFactor struct {
+				ID           uint `gorm:"primaryKey"`
+				CreatedAt    time.Time
+				UserID       uint   `gorm:"not null;uniqueIndex"`
+				Secret       string `gorm:"type:VARCHAR(64);not null"`
+				ConfirmedAt  *time.Time
+				LastUsedStep int64 `gorm:"not null;default:0"`
+			}
+			type RecoveryCode struct {
+				ID        uint `gorm:"primaryKey"`
+				CreatedAt time.Time
+				UserID    uint   `gorm:"not null;index"`
+				CodeHash  string `gorm:"type:VARCHAR(64);not null;uniqueIndex"`
+				UsedAt    *time.Time
+			}
+			return tx.Migrator().CreateTable(&TOTPFactor{}, &RecoveryCode{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("recovery_codes", "totp_factors")
+		},
+	})
+}
@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20231205000001",
+		Name:    "create_webauthn",
+		Up: func(tx *gorm.DB) error {
+			type WebAuthnCredential struct {
+				ID              uint `gorm:"primaryKey"`
+				CreatedAt       time.Time
+				UserID          uint   `gorm:"not null;index"`
+				Name            string `gorm:"type:VARCHAR(64);not null"`
+				CredentialID    string `gorm:"type:VARCHAR(255);not null;uniqueIndex"`
+				PublicKey       []byte `gorm:"not null"`
+				AttestationType string `gorm:"type:VARCHAR(32)"`
+				Transports      string `gorm:"type:VARCHAR(128)"`
+				AAGUID          []byte
+				SignCount       uint32 `gorm:"not null;default:0"`
+				BackupEligible  bool   `gorm:"not null;default:false"`
+				BackupState     bool   `gorm:"not null;default:false"`
+				LastUsedAt      *time.Time
+			}
+			type WebAuthnSession struct {
+				ID        uint `gorm:"primaryKey"`
+				CreatedAt time.Time
+				Challenge string `gorm:"type:VARCHAR(128);not null;uniqueIndex"`
+				Ceremony  string `gorm:"type:VARCHAR(16);not null"`
+				UserID    uint
+				Name      string    `gorm:"type:VARCHAR(64)"`
+				Data      string    `gorm:"type:TEXT;not null"`
+				ExpiresAt time.Time `gorm:"not null;index"`
+			}
+			return tx.Migrator().CreateTable(&WebAuthnCredential{}, &WebAuthnSession{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("web_authn_sessions", "web_authn_credentials")
+		},
+	})
+}
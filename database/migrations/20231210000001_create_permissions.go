@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20231210000001",
+		Name:    "create_permissions",
+		Up: func(tx *gorm.DB) error {
+			type Permission struct {
+				ID          uint `gorm:"primaryKey"`
+				CreatedAt   time.Time
+				Name        string `gorm:"type:VARCHAR(64);not null;uniqueIndex"`
+				Description string `gorm:"type:VARCHAR(100)"`
+			}
+			type RolePermission struct {
+				UserRoleID   uint `gorm:"primaryKey;autoIncrement:false"`
+				PermissionID uint `gorm:"primaryKey;autoIncrement:false;index"`
+			}
+			return tx.Migrator().CreateTable(&Permission{}, &RolePermission{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("role_permissions", "permissions")
+		},
+	})
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20231215000001",
+		Name:    "create_user_role_grants",
+		Up: func(tx *gorm.DB) error {
+			type UserRoleGrant struct {
+				ID         uint `gorm:"primaryKey"`
+				CreatedAt  time.Time
+				UserID     uint       `gorm:"not null;uniqueIndex:idx_user_role_grant"`
+				UserRoleID uint       `gorm:"not null;uniqueIndex:idx_user_role_grant;index"`
+				ExpiresAt  *time.Time `gorm:"index"`
+				GrantedBy  uint
+			}
+			return tx.Migrator().CreateTable(&UserRoleGrant{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("user_role_grants")
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20231220000001",
+		Name:    "create_personal_access_tokens",
+		Up: func(tx *gorm.DB) error {
+			type PersonalAccessToken struct {
+				ID         uint `gorm:"primaryKey"`
+				CreatedAt  time.Time
+				UserID     uint      `gorm:"not null;index"`
+				Name       string    `gorm:"type:VARCHAR(64);not null"`
+				Prefix     string    `gorm:"type:VARCHAR(16);not null;uniqueIndex"`
+				TokenHash  string    `gorm:"type:VARCHAR(64);not null"`
+				Scopes     string    `gorm:"type:TEXT;not null"`
+				ExpiresAt  time.Time `gorm:"not null"`
+				LastUsedAt *time.Time
+				RevokedAt  *time.Time
+			}
+			return tx.Migrator().CreateTable(&PersonalAccessToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("personal_access_tokens")
+		},
+	})
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20231225000001",
+		Name:    "create_login_throttles",
+		Up: func(tx *gorm.DB) error {
+			type LoginThrottle struct {
+				Target        string    `gorm:"type:VARCHAR(80);primaryKey"`
+				Failures      int       `gorm:"not null;default:0"`
+				LastFailureAt time.Time `gorm:"not null;index"`
+				RetryAt       *time.Time
+				LockedUntil   *time.Time
+			}
+			return tx.Migrator().CreateTable(&LoginThrottle{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("login_throttles")
+		},
+	})
+}
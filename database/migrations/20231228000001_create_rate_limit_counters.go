@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20231228000001",
+		Name:    "create_rate_limit_counters",
+		Up: func(tx *gorm.DB) error {
+			type RateLimitCounter struct {
+				Bucket  string    `gorm:"type:VARCHAR(191);primaryKey"`
+				Count   int       `gorm:"not null;default:0"`
+				ResetAt time.Time `gorm:"not null;index"`
+			}
+			return tx.Migrator().CreateTable(&RateLimitCounter{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("rate_limit_counters")
+		},
+	})
+}
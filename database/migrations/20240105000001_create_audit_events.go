@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20240105000001",
+		Name:    "create_audit_events",
+		Up: func(tx *gorm.DB) error {
+			type AuditEvent struct {
+				ID         uint      `gorm:"primaryKey"`
+				CreatedAt  time.Time `gorm:"not null;index"`
+				Type       string    `gorm:"type:VARCHAR(64);not null;index"`
+				ActorID    *uint     `gorm:"index"`
+				TargetType string    `gorm:"type:VARCHAR(32);index:idx_audit_target"`
+				TargetID   *uint     `gorm:"index:idx_audit_target"`
+				IP         string    `gorm:"type:VARCHAR(45)"`
+				UserAgent  string    `gorm:"type:VARCHAR(255)"`
+				RequestID  string    `gorm:"type:VARCHAR(64);index"`
+				Changes    string    `gorm:"type:TEXT"`
+				Metadata   string    `gorm:"type:TEXT"`
+			}
+			return tx.Migrator().CreateTable(&AuditEvent{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("audit_events")
+		},
+	})
+}
@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(&Migration{
+		Version: "20240110000001",
+		Name:    "chain_audit_events",
+		Up: func(tx *gorm.DB) error {
+			// Events Already Recorded Stay Unchained - The Chain Starts With The Next One
+			type AuditEvent struct {
+				PrevHash string `gorm:"type:VARCHAR(64);not null;default:''"`
+				Hash     string `gorm:"type:VARCHAR(64);not null;default:''"`
+			}
+			for _, column := range []string{"PrevHash", "Hash"} {
+				err := tx.Migrator().AddColumn(&AuditEvent{}, column)
+				if err != nil {
+					return err
+				}
+			}
+
+			type AuditChainHead struct {
+				ID      uint   `gorm:"primaryKey"`
+				EventID uint   `gorm:"not null;default:0"`
+				Hash    string `gorm:"type:VARCHAR(64);not null;default:''"`
+			}
+			err := tx.Migrator().CreateTable(&AuditChainHead{})
+			if err != nil {
+				return err
+			}
+			err = tx.Create(&AuditChainHead{ID: 1}).Error
+			if err != nil {
+				return err
+			}
+
+			type AuditCheckpoint struct {
+				ID        uint `gorm:"primaryKey"`
+				CreatedAt time.Time
+				EventID   uint   `gorm:"not null;uniqueIndex"`
+				Hash      string `gorm:"type:VARCHAR(64);not null"`
+				Signature string `gorm:"type:TEXT;not null"`
+			}
+			return tx.Migrator().CreateTable(&AuditCheckpoint{})
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, table := range []string{"audit_checkpoints", "audit_chain_heads"} {
+				err := tx.Migrator().DropTable(table)
+				if err != nil {
+					return err
+				}
+			}
+			type AuditEvent struct {
+				PrevHash string
+				Hash     string
+			}
+			for _, column := range []string{"Hash", "PrevHash"} {
+				err := tx.Migrator().DropColumn(&AuditEvent{}, column)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+	migrationTemplate = template.Must(template.New("migration").Parse(`package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	Register(&Migration{
+		Version: "{{ .Version }}",
+		Name:    "{{ .Name }}",
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`))
+)
+
+// Writes An Empty Migration Into dir - Returns The File Path
+func Create(dir string, name string) (string, error) {
+	name = strings.Trim(nonWord.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", errors.New("Migration Name Required")
+	}
+
+	version := time.Now().UTC().Format("20060102150405")
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s.go", version, name))
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	err = migrationTemplate.Execute(f, struct{ Version, Name string }{version, name})
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,249 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/*
+A Versioned Schema Change - Up And Down Run Inside A Transaction
+Migrations Should Declare Their Own Snapshot Of Any Struct They Migrate
+So Later Model Changes Don't Rewrite History
+*/
+type Migration struct {
+	Version string // UTC Timestamp - 20060102150405
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// Records Every Applied Migration
+type SchemaMigration struct {
+	Version   string    `gorm:"type:VARCHAR(14);primaryKey"`
+	Name      string    `gorm:"type:VARCHAR(255);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// Single Row Table - Whoever Inserts ID 1 Holds The Lock
+type SchemaMigrationLock struct {
+	ID       uint      `gorm:"primaryKey;autoIncrement:false"`
+	Holder   string    `gorm:"type:VARCHAR(255);not null"`
+	LockedAt time.Time `gorm:"not null"`
+}
+
+type Status struct {
+	Version   string
+	Name      string
+	AppliedAt *time.Time
+	Missing   bool // Applied But No Longer Registered
+}
+
+const lockID = 1
+
+var (
+	// Locks Older Than This Are Assumed To Belong To A Crashed Instance
+	LockTimeout = 5 * time.Minute
+	// How Long To Wait For Another Instance To Finish
+	LockWait = 2 * time.Minute
+
+	registry = map[string]*Migration{}
+)
+
+// Called From Each Migration File's init
+func Register(m *Migration) {
+	if _, exists := registry[m.Version]; exists {
+		panic(fmt.Sprintf("Duplicate Migration Version: %s", m.Version))
+	}
+	registry[m.Version] = m
+}
+
+// Every Registered Migration In Version Order
+func All() []*Migration {
+	all := make([]*Migration, 0, len(registry))
+	for _, m := range registry {
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+// Applies Every Pending Migration - Returns The Ones Applied
+func Up(db *gorm.DB) ([]*Migration, error) {
+	var ran []*Migration
+	err := withLock(db, func() error {
+		applied, err := appliedVersions(db)
+		if err != nil {
+			return err
+		}
+		for _, m := range All() {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			err = db.Transaction(func(tx *gorm.DB) error {
+				err := m.Up(tx)
+				if err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("Migration %s_%s Failed: %w", m.Version, m.Name, err)
+			}
+			ran = append(ran, m)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Rolls Back The Last steps Applied Migrations - Returns The Ones Rolled Back
+func Down(db *gorm.DB, steps int) ([]*Migration, error) {
+	var ran []*Migration
+	err := withLock(db, func() error {
+		var records []SchemaMigration
+		err := db.Order("version DESC").Limit(steps).Find(&records).Error
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			m, ok := registry[record.Version]
+			if !ok {
+				return fmt.Errorf("Migration %s_%s Is Applied But Not Registered", record.Version, record.Name)
+			}
+			err = db.Transaction(func(tx *gorm.DB) error {
+				err := m.Down(tx)
+				if err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{}, "version = ?", m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("Rollback %s_%s Failed: %w", m.Version, m.Name, err)
+			}
+			ran = append(ran, m)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Lists Registered And Applied Migrations In Version Order
+func GetStatus(db *gorm.DB) ([]Status, error) {
+	err := ensureTables(db)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, m := range All() {
+		s := Status{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			s.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, s)
+	}
+	for version, record := range applied {
+		if _, ok := registry[version]; !ok {
+			appliedAt := record.AppliedAt
+			statuses = append(statuses, Status{Version: version, Name: record.Name, AppliedAt: &appliedAt, Missing: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Number Of Registered Migrations Not Yet Applied
+func Pending(db *gorm.DB) (int, error) {
+	statuses, err := GetStatus(db)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func appliedVersions(db *gorm.DB) (map[string]SchemaMigration, error) {
+	var records []SchemaMigration
+	err := db.Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[string]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// Creates The Bookkeeping Tables - Retried Once In Case Another Instance Raced Us
+func ensureTables(db *gorm.DB) error {
+	err := db.AutoMigrate(&SchemaMigration{}, &SchemaMigrationLock{})
+	if err != nil {
+		err = db.AutoMigrate(&SchemaMigration{}, &SchemaMigrationLock{})
+	}
+	return err
+}
+
+// Holds The Migration Lock While fn Runs
+func withLock(db *gorm.DB, fn func() error) error {
+	err := ensureTables(db)
+	if err != nil {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	holder := fmt.Sprintf("%s:%d:%d", host, os.Getpid(), time.Now().UnixNano())
+	deadline := time.Now().Add(LockWait)
+
+	for {
+		lock := SchemaMigrationLock{ID: lockID, Holder: holder, LockedAt: time.Now()}
+		if db.Create(&lock).Error == nil {
+			break
+		}
+
+		// Clear Locks Left Behind By Crashed Instances
+		db.Where("id = ? AND locked_at < ?", lockID, time.Now().Add(-LockTimeout)).Delete(&SchemaMigrationLock{})
+
+		if time.Now().After(deadline) {
+			return errors.New("Timed Out Waiting For The Migration Lock")
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	defer db.Where("id = ? AND holder = ?", lockID, holder).Delete(&SchemaMigrationLock{})
+
+	// Keep The Lock Fresh So Long Migrations Aren't Mistaken For A Crashed Instance
+	done := make(chan struct{})
+	defer close(done)
+	go heartbeat(db, holder, done)
+
+	return fn()
+}
+
+// Bumps locked_at Every Third Of LockTimeout Until done Closes
+func heartbeat(db *gorm.DB, holder string, done <-chan struct{}) {
+	ticker := time.NewTicker(LockTimeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			db.Model(&SchemaMigrationLock{}).Where("id = ? AND holder = ?", lockID, holder).Update("locked_at", time.Now())
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package migrations
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"app/config"
+	"app/database"
+
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	db, err := database.Open(config.DBConfig{
+		Driver:   config.DriverSQLite,
+		Database: filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatalf("\nFailed To Open Database: %s\n", err.Error())
+	}
+	return db
+}
+
+func TestUpDownStatus(t *testing.T) {
+	db := openTestDB(t)
+
+	ran, err := Up(db)
+	if err != nil {
+		t.Fatalf("\nFailed To Migrate Up: %s\n", err.Error())
+	}
+	if len(ran) != len(All()) {
+		t.Fatalf("\nApplied %d Migrations Expected: %d\n", len(ran), len(All()))
+	}
+	if !db.Migrator().HasTable("users") {
+		t.Fatalf("\nExpected users Table\n")
+	}
+
+	// Running Again Is A No-Op
+	ran, err = Up(db)
+	if err != nil || len(ran) != 0 {
+		t.Fatalf("\nExpected Nothing To Migrate, Got: %d %v\n", len(ran), err)
+	}
+
+	last := All()[len(All())-1]
+	ran, err = Down(db, 1)
+	if err != nil {
+		t.Fatalf("\nFailed To Migrate Down: %s\n", err.Error())
+	}
+	if len(ran) != 1 || ran[0].Version != last.Version {
+		t.Fatalf("\nExpected To Roll Back %s\n", last.Version)
+	}
+
+	pending, err := Pending(db)
+	if err != nil || pending != 1 {
+		t.Fatalf("\nPending: %d Expected: 1 (%v)\n", pending, err)
+	}
+}
+
+// Two Instances Booting At Once Must Apply Each Migration Exactly Once
+func TestConcurrentUp(t *testing.T) {
+	db := openTestDB(t)
+
+	var wg sync.WaitGroup
+	applied := make([]int, 2)
+	errs := make([]error, 2)
+	for i := range applied {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ran, err := Up(db)
+			applied[i], errs[i] = len(ran), err
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("\nConcurrent Up Failed: %s\n", err.Error())
+		}
+	}
+	if applied[0]+applied[1] != len(All()) {
+		t.Fatalf("\nApplied %d Migrations Expected: %d\n", applied[0]+applied[1], len(All()))
+	}
+}
+
+func TestStaleLockIsCleared(t *testing.T) {
+	db := openTestDB(t)
+	err := ensureTables(db)
+	if err != nil {
+		t.Fatalf("\nFailed To Create Tables: %s\n", err.Error())
+	}
+
+	// Lock Left Behind By A Crashed Instance
+	db.Create(&SchemaMigrationLock{ID: lockID, Holder: "crashed", LockedAt: time.Now().Add(-2 * LockTimeout)})
+
+	_, err = Up(db)
+	if err != nil {
+		t.Fatalf("\nExpected Stale Lock To Be Cleared: %s\n", err.Error())
+	}
+}
+
+// Turning On require_verified_email Mustn't Lock Out Existing Accounts
+func TestEmailVerificationBackfill(t *testing.T) {
+	db := openTestDB(t)
+	_, err := Up(db)
+	if err != nil {
+		t.Fatalf("\nFailed To Migrate Up: %s\n", err.Error())
+	}
+
+	// Back To Just Before Verification, Then Sign Someone Up
+	later := 0
+	for _, m := range All() {
+		if m.Version >= "20231125000001" {
+			later++
+		}
+	}
+	_, err = Down(db, later)
+	if err != nil {
+		t.Fatalf("\nFailed To Migrate Down: %s\n", err.Error())
+	}
+	createdAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	db.Exec("INSERT INTO user_roles (id, role) VALUES (1, 'default')")
+	err = db.Exec("INSERT INTO users (username, password, email, role_id, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+		"existing", "hash", "existing@example.com", 1, createdAt, createdAt).Error
+	if err != nil {
+		t.Fatalf("\nFailed To Insert User: %s\n", err.Error())
+	}
+
+	_, err = Up(db)
+	if err != nil {
+		t.Fatalf("\nFailed To Migrate Up: %s\n", err.Error())
+	}
+	var verifiedAt *time.Time
+	db.Raw("SELECT email_verified_at FROM users WHERE username = ?", "existing").Scan(&verifiedAt)
+	if verifiedAt == nil || !verifiedAt.Equal(createdAt) {
+		t.Fatalf("\nUnexpected email_verified_at: %v Expected: %s\n", verifiedAt, createdAt)
+	}
+}
+
+// A Migration Outlasting LockTimeout Keeps The Lock
+func TestLongMigrationKeepsLock(t *testing.T) {
+	db := openTestDB(t)
+	previous := LockTimeout
+	LockTimeout = 300 * time.Millisecond
+	t.Cleanup(func() { LockTimeout = previous })
+
+	var wg sync.WaitGroup
+	var firstEnd, secondStart time.Time
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		err := withLock(db, func() error {
+			time.Sleep(4 * LockTimeout)
+			firstEnd = time.Now()
+			return nil
+		})
+		if err != nil {
+			t.Errorf("\nFirst Holder Failed: %s\n", err.Error())
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		time.Sleep(LockTimeout / 3)
+		err := withLock(db, func() error {
+			secondStart = time.Now()
+			return nil
+		})
+		if err != nil {
+			t.Errorf("\nSecond Holder Failed: %s\n", err.Error())
+		}
+	}()
+	wg.Wait()
+
+	if secondStart.Before(firstEnd) {
+		t.Fatalf("\nLock Was Taken While The First Migration Was Still Running\n")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	path, err := Create(dir, "Add Users Nickname")
+	if err != nil {
+		t.Fatalf("\nFailed To Create Migration: %s\n", err.Error())
+	}
+	matched, _ := filepath.Match(filepath.Join(dir, "*_add_users_nickname.go"), path)
+	if !matched {
+		t.Fatalf("\nUnexpected Migration Path: %s\n", path)
+	}
+}
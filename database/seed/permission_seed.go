@@ -0,0 +1,57 @@
+package seed
+
+import (
+	"app/database"
+	"app/models/user"
+	"log"
+)
+
+// Permissions The Seeded Roles Start With - admin Gets Every Permission
+var defaultPermissions = []struct {
+	Name        string
+	Description string
+	Roles       []string
+}{
+	{user.PermUsersRead, "List and view any account", nil},
+	{user.PermUsersWrite, "Update any account, its role and status", nil},
+	{user.PermRolesRead, "List roles and their permissions", nil},
+	{user.PermRolesWrite, "Create, change and delete roles", nil},
+	{user.PermAuditRead, "Search the audit log", nil},
+}
+
+/*
+Adds Any Permission That Doesn't Exist Yet And Grants It To Its Roles
+Existing Permissions Are Left Alone So Changes Made Through The API Stick
+*/
+func SeedPermissionTable() {
+	for _, p := range defaultPermissions {
+		var count int64
+		err := database.DB.Model(&user.Permission{}).Where("name = ?", p.Name).Count(&count).Error
+		if err != nil {
+			log.Fatalf(`Unable To Read Permission: %v`, err.Error())
+		}
+
+		// Already Seeded
+		if count != 0 {
+			continue
+		}
+
+		permission := user.Permission{Name: p.Name, Description: p.Description}
+		err = database.DB.Create(&permission).Error
+		if err != nil {
+			log.Fatalf(`Error Seeding Permission: %v`, err.Error())
+		}
+
+		var roles []user.UserRole
+		err = database.DB.Where("role IN ?", append(p.Roles, "admin")).Find(&roles).Error
+		if err != nil {
+			log.Fatalf(`Unable To Read UserRole: %v`, err.Error())
+		}
+		for _, role := range roles {
+			err = database.DB.Model(&role).Association("Permissions").Append(&permission)
+			if err != nil {
+				log.Fatalf(`Error Granting Permission %s: %v`, p.Name, err.Error())
+			}
+		}
+	}
+}
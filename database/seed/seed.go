@@ -1,12 +1,12 @@
 package seed
 
 import (
-	"fmt"
+	"app/logging"
 )
 
+// Tables Are Created By database/migrations - Seed Only Inserts Default Rows
 func Seed() {
-	// AutoMigrate And Seed Tables
 	SeedUserRoleTable()
-	SeedUserTable()
-	fmt.Println("Successfully Seeded Database")
+	SeedPermissionTable()
+	logging.Get().Info("Successfully Seeded Database")
 }
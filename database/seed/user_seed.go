@@ -7,16 +7,15 @@ import (
 )
 
 func SeedUserRoleTable() {
-	err := database.DB.AutoMigrate(&user.UserRole{})
+	// Check To See If Already Seeded
+	var count int64
+	err := database.DB.Model(&user.UserRole{}).Count(&count).Error
 	if err != nil {
-		log.Fatalf(`Unable To Migrate UserRole: %v`, err.Error())
+		log.Fatalf(`Unable To Read UserRole: %v`, err.Error())
 	}
-	// Check To See If Already Seeded
-	var userRole user.UserRole
-	err = database.DB.Take(&userRole).Error
 
 	// Table Is Already Seeded
-	if userRole.ID != 0 {
+	if count != 0 {
 		return
 	}
 
@@ -32,11 +31,3 @@ func SeedUserRoleTable() {
 	}
 
 }
-
-func SeedUserTable() {
-	err := database.DB.AutoMigrate(&user.User{})
-
-	if err != nil {
-		log.Fatalf(`Error Migrating User: %v`, err.Error())
-	}
-}
@@ -0,0 +1,69 @@
+/*
+Leveled Structured Logs - JSON In PROD, Text Otherwise. Handlers Log Through
+For(c) So Every Line Carries The Request ID. Secrets, Tokens And Email
+Addresses Are Redacted On The Way Out - See redact.go
+*/
+package logging
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/config"
+)
+
+var logger atomic.Pointer[slog.Logger]
+
+// Builds The Logger For cfg, Makes It Current And Routes The log Package Through It
+func Configure(cfg *config.Config) {
+	l := New(cfg, os.Stderr)
+	Set(l)
+	// Lines From The log Package Come Through At info
+	slog.SetDefault(l)
+}
+
+func New(cfg *config.Config, w io.Writer) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level(cfg), ReplaceAttr: redactAttr}
+	if cfg.Log.FormatFor(cfg.App.Mode) == config.LogJSON {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
+
+func level(cfg *config.Config) slog.Level {
+	if cfg.App.Debug {
+		return slog.LevelDebug
+	}
+	var l slog.Level
+	err := l.UnmarshalText([]byte(cfg.Log.Level))
+	if err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+func Set(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// Current Logger - Built From The Loaded Config Until Configure Or Set Runs
+func Get() *slog.Logger {
+	l := logger.Load()
+	if l == nil {
+		Set(New(config.Get(), os.Stderr))
+		l = logger.Load()
+	}
+	return l
+}
+
+// The Request's Logger - Tagged With Its request_id By RequestID
+func For(c *fiber.Ctx) *slog.Logger {
+	if l, ok := c.Locals(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return Get()
+}
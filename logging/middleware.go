@@ -0,0 +1,135 @@
+package logging
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/goccy/go-json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+const (
+	requestIDKey = "requestid"
+	loggerKey    = "logger"
+)
+
+// Longest X-Request-ID Taken From A Client - Anything Else Gets A Fresh One
+const maxRequestIDLength = 64
+
+/*
+Tags Each Request With An ID - The Client's X-Request-ID When It Sent A Sane One -
+Echoes It Back And Hands Handlers A Logger That Includes It
+*/
+func RequestID() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(fiber.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = utils.UUIDv4()
+		}
+		c.Set(fiber.HeaderXRequestID, id)
+		c.Locals(requestIDKey, id)
+		c.Locals(loggerKey, Get().With("request_id", id))
+		return c.Next()
+	}
+}
+
+// Adds args To Every Line The Request Logs From Here On
+func With(c *fiber.Ctx, args ...any) {
+	c.Locals(loggerKey, For(c).With(args...))
+}
+
+// The Request's ID - Empty Outside RequestID
+func RequestIDFrom(c *fiber.Ctx) string {
+	id, _ := c.Locals(requestIDKey).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) || r == ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+// One Line Per Request With Its Status And Latency - 5xx At error, The Rest At info
+func AccessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		if err != nil {
+			// Settle The Status Now Rather Than After The Line Is Written
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		level := slog.LevelInfo
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		attrs := []any{
+			"method", c.Method(),
+			"path", c.Path(),
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"ip", c.IP(),
+			"bytes", len(c.Response().Body()),
+		}
+		if user_id, ok := c.Locals("user_id").(string); ok {
+			attrs = append(attrs, "user_id", user_id)
+		}
+		For(c).Log(c.UserContext(), level, "Request", attrs...)
+		return nil
+	}
+}
+
+/*
+Turns Every Error Response Into {"error": ..., "request_id": ...} So Reports
+Can Be Matched To Logs. Errors Handlers Return Unhandled Are Logged And
+Answered With A Plain 500 So Nothing Internal Leaks Out
+*/
+func Errors() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		if err != nil {
+			var fiberErr *fiber.Error
+			if !errors.As(err, &fiberErr) {
+				For(c).Error("Unhandled Error", "error", err)
+				fiberErr = fiber.ErrInternalServerError
+			}
+			return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message, "request_id": RequestIDFrom(c)})
+		}
+
+		status := c.Response().StatusCode()
+		if status < fiber.StatusBadRequest {
+			return nil
+		}
+
+		body := c.Response().Body()
+		if strings.HasPrefix(string(c.Response().Header.ContentType()), fiber.MIMEApplicationJSON) {
+			var response map[string]interface{}
+			if json.Unmarshal(body, &response) != nil || response["error"] == nil || response["request_id"] != nil {
+				return nil
+			}
+			response["request_id"] = RequestIDFrom(c)
+			return c.JSON(response)
+		}
+
+		// c.SendStatus Bodies
+		message := string(body)
+		if message == "" || message == utils.StatusMessage(status) {
+			return c.JSON(fiber.Map{"error": utils.StatusMessage(status), "request_id": RequestIDFrom(c)})
+		}
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Stands In For Anything That Mustn't Reach The Logs
+const Redacted = "[REDACTED]"
+
+// Keys Never Logged Or Audited - Also Any Ending In One Of secretSuffixes
+var secretKeys = map[string]bool{
+	"password":       true,
+	"token":          true,
+	"refresh_token":  true,
+	"mfa_token":      true,
+	"authorization":  true,
+	"code":           true,
+	"secret":         true,
+	"recovery_codes": true,
+}
+
+var secretSuffixes = []string{"_hash", "_secret", "_password", "_token"}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// Personal Access Tokens And JWTs Wherever They Turn Up
+	tokenPattern = regexp.MustCompile(`uap_[0-9a-f]+_[A-Za-z0-9_\-]+|eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*`)
+)
+
+// Whether A Key Or Field Name Holds A Secret - Shared With The Audit Log
+func Secret(key string) bool {
+	key = strings.ToLower(key)
+	if secretKeys[key] {
+		return true
+	}
+	for _, suffix := range secretSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// slog.HandlerOptions.ReplaceAttr - Runs On Every Attribute, Message Included
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if Secret(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	value := a.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, redactString(value.String()))
+	case slog.KindAny:
+		// Errors Often Quote What Went Wrong
+		if err, ok := value.Any().(error); ok {
+			return slog.String(a.Key, redactString(err.Error()))
+		}
+	}
+	return a
+}
+
+// Masks Email Addresses And Blanks Out Tokens In s
+func redactString(s string) string {
+	s = tokenPattern.ReplaceAllString(s, Redacted)
+	return emailPattern.ReplaceAllStringFunc(s, maskEmail)
+}
+
+// j***@example.com - Enough To Tell Accounts Apart In An Incident
+func maskEmail(email string) string {
+	local, domain, _ := strings.Cut(email, "@")
+	if local == "" {
+		return "***@" + domain
+	}
+	return local[:1] + "***@" + domain
+}
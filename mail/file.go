@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+/*
+Writes Each Message Into A Maildir - For Development, Point A
+Mail Client At dir Or Just Open The Files In new/
+*/
+type FileSender struct {
+	Dir  string
+	From string
+}
+
+func NewFileSender(dir string, from string) *FileSender {
+	return &FileSender{Dir: dir, From: from}
+}
+
+var deliveries atomic.Uint64
+
+func (s *FileSender) Send(msg Message) error {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		err := os.MkdirAll(filepath.Join(s.Dir, sub), 0755)
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := msg.Bytes(s.From)
+	if err != nil {
+		return err
+	}
+
+	// Written To tmp/ Then Moved So Readers Never See Half A Message
+	hostname, _ := os.Hostname()
+	name := fmt.Sprintf("%d.M%dP%dQ%d.%s.eml", time.Now().Unix(), time.Now().Nanosecond()/1000, os.Getpid(), deliveries.Add(1), hostname)
+	tmp := filepath.Join(s.Dir, "tmp", name)
+
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.Dir, "new", name))
+}
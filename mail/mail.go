@@ -0,0 +1,139 @@
+/*
+Outgoing Mail - Handlers Queue Rendered Templates In The Outbox (See Queue)
+And A Background Worker Hands Them To The Configured Sender
+*/
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"app/config"
+	"app/logging"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string // Optional
+}
+
+// A Transport - SMTP, A Maildir Dump Or Memory For Tests
+type Sender interface {
+	Send(msg Message) error
+}
+
+var sender atomic.Pointer[Sender]
+
+// Builds The Sender For The Configured Transport And Makes It Current
+func Configure(cfg *config.Config) error {
+	var s Sender
+	switch cfg.Mail.TransportFor(cfg.SMTP) {
+	case config.MailSMTP:
+		s = NewSMTPSender(cfg.SMTP, cfg.Mail.From)
+	case config.MailFile:
+		s = NewFileSender(cfg.Mail.Dir, cfg.Mail.From)
+	case config.MailLog:
+		s = LogSender{}
+	default:
+		return fmt.Errorf("Unknown Mail Transport: %q", cfg.Mail.Transport)
+	}
+	SetSender(s)
+	return nil
+}
+
+func SetSender(s Sender) {
+	sender.Store(&s)
+}
+
+// Current Sender - Logs Until Configure Or SetSender Runs
+func Current() Sender {
+	s := sender.Load()
+	if s == nil {
+		return LogSender{}
+	}
+	return *s
+}
+
+// Drops Messages - Logs Them When Debugging
+type LogSender struct{}
+
+func (LogSender) Send(msg Message) error {
+	logging.Get().Debug("Mail Transport Is log - Not Sending", "subject", msg.Subject, "to", msg.To)
+	return nil
+}
+
+// RFC 5322 Message - multipart/alternative When There's An HTML Part
+func (msg Message) Bytes(from string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from)
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(from))
+	header("MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		header("Content-Type", `text/plain; charset="utf-8"`)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		err := writeQuotedPrintable(&buf, msg.Text)
+		return buf.Bytes(), err
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", `multipart/alternative; boundary="`+parts.Boundary()+`"`)
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + `; charset="utf-8"`},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = writeQuotedPrintable(w, part.body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := parts.Close()
+	return buf.Bytes(), err
+}
+
+func writeQuotedPrintable(w interface{ Write([]byte) (int, error) }, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	_, err := qp.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	if err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func messageID(from string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at != -1 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
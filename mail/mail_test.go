@@ -0,0 +1,198 @@
+package mail_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"app/config"
+	"app/database"
+	"app/mail"
+	"app/testutil"
+)
+
+var resetData = map[string]interface{}{"Link": "https://example.com/reset?token=a&b=<c>", "Minutes": 60}
+
+func TestRenderLocales(t *testing.T) {
+	cases := []struct {
+		locale   string
+		expected string
+	}{
+		{"", "Reset your password"},
+		{"en", "Reset your password"},
+		{"es", "Restablece tu contraseña"},
+		{"es-MX", "Restablece tu contraseña"},
+		{"fr", "Reset your password"}, // Falls Back
+	}
+	for _, tc := range cases {
+		t.Run(tc.locale, func(t *testing.T) {
+			msg, err := mail.Render("tester@tester.com", "password_reset", tc.locale, resetData)
+			if err != nil {
+				t.Fatalf("\nFailed To Render: %s\n", err.Error())
+			}
+			if msg.Subject != tc.expected {
+				t.Fatalf("\nUnexpected Subject: %q Expected: %q\n", msg.Subject, tc.expected)
+			}
+		})
+	}
+
+	msg, _ := mail.Render("tester@tester.com", "password_reset", "en", resetData)
+	if !strings.Contains(msg.Text, "https://example.com/reset?token=a&b=<c>") {
+		t.Fatalf("\nText Part Shouldn't Be Escaped: %s\n", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "<!DOCTYPE html>") || strings.Contains(msg.HTML, "<c>") {
+		t.Fatalf("\nHTML Part Should Use The Layout And Escape Data: %s\n", msg.HTML)
+	}
+
+	_, err := mail.Render("tester@tester.com", "no_such_template", "en", nil)
+	if err == nil {
+		t.Fatalf("\nExpected Unknown Template To Fail\n")
+	}
+}
+
+func TestMessageBytes(t *testing.T) {
+	msg, _ := mail.Render("tester@tester.com", "password_reset", "es", resetData)
+	data, err := msg.Bytes("no-reply@example.com")
+	if err != nil {
+		t.Fatalf("\nFailed To Build Message: %s\n", err.Error())
+	}
+
+	parsed, err := netmail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("\nInvalid Message: %s\n", err.Error())
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if subject != msg.Subject || parsed.Header.Get("To") != msg.To || parsed.Header.Get("Message-Id") == "" {
+		t.Fatalf("\nInvalid Headers: %v\n", parsed.Header)
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("\nUnexpected Content-Type: %s\n", mediaType)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var types []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("\nInvalid Part: %s\n", err.Error())
+		}
+		types = append(types, strings.Split(part.Header.Get("Content-Type"), ";")[0])
+	}
+	if strings.Join(types, ",") != "text/plain,text/html" {
+		t.Fatalf("\nUnexpected Parts: %v\n", types)
+	}
+}
+
+func TestFileSender(t *testing.T) {
+	dir := t.TempDir()
+	sender := mail.NewFileSender(dir, "no-reply@example.com")
+
+	for i := 0; i < 2; i++ {
+		err := sender.Send(mail.Message{To: "tester@tester.com", Subject: "Hello", Text: "Hi"})
+		if err != nil {
+			t.Fatalf("\nFailed To Send: %s\n", err.Error())
+		}
+	}
+
+	delivered, _ := os.ReadDir(filepath.Join(dir, "new"))
+	pending, _ := os.ReadDir(filepath.Join(dir, "tmp"))
+	if len(delivered) != 2 || len(pending) != 0 {
+		t.Fatalf("\nExpected 2 Messages In new/ And None In tmp/: %d %d\n", len(delivered), len(pending))
+	}
+}
+
+// Failed Sends Are Retried With Backoff, Then Given Up On
+func TestOutboxRetries(t *testing.T) {
+	cfg := testutil.Config(t)
+	cfg.Mail.MaxAttempts = 2
+	testutil.SetupWith(t, cfg)
+
+	failing := &mail.MemorySender{Err: errors.New("Connection Refused")}
+	mail.SetSender(failing)
+
+	err := mail.Enqueue(context.Background(), mail.Message{To: "tester@tester.com", Subject: "Hello", Text: "Secret Link"})
+	if err != nil {
+		t.Fatalf("\nFailed To Enqueue: %s\n", err.Error())
+	}
+
+	attempted, _ := mail.ProcessOutbox()
+	var queued mail.OutboxMessage
+	database.DB.First(&queued)
+	if attempted != 1 || queued.Attempts != 1 || queued.LastError == "" || queued.LockedUntil != nil {
+		t.Fatalf("\nFailure Not Recorded: %+v\n", queued)
+	}
+	if wait := time.Until(queued.NextAttemptAt); wait < 25*time.Second {
+		t.Fatalf("\nRetry Scheduled Too Soon: %s\n", wait)
+	}
+
+	// Not Due Yet
+	attempted, _ = mail.ProcessOutbox()
+	if attempted != 0 {
+		t.Fatalf("\nRetried Before The Backoff Elapsed\n")
+	}
+
+	// Second Failure Hits max_attempts
+	database.DB.Model(&queued).Update("next_attempt_at", time.Now().Add(-time.Second))
+	mail.ProcessOutbox()
+	database.DB.First(&queued)
+	if queued.FailedAt == nil || queued.Text != "" {
+		t.Fatalf("\nExpected Message To Be Given Up On And Cleared: %+v\n", queued)
+	}
+
+	// A Working Transport Delivers And Clears The Body
+	working := new(mail.MemorySender)
+	mail.SetSender(working)
+	mail.Enqueue(context.Background(), mail.Message{To: "tester@tester.com", Subject: "Hello Again", Text: "Secret Link"})
+	mail.ProcessOutbox()
+
+	var sent mail.OutboxMessage
+	database.DB.Where("subject = ?", "Hello Again").First(&sent)
+	if len(working.Messages()) != 1 || sent.SentAt == nil || sent.Text != "" {
+		t.Fatalf("\nExpected Message To Be Sent And Cleared: %+v\n", sent)
+	}
+}
+
+// A Message Claimed By Another Worker Is Left Alone Until Its Claim Lapses
+func TestOutboxClaim(t *testing.T) {
+	testutil.Setup(t)
+	sender := new(mail.MemorySender)
+	mail.SetSender(sender)
+
+	mail.Enqueue(context.Background(), mail.Message{To: "tester@tester.com", Subject: "Hello", Text: "Hi"})
+	database.DB.Model(&mail.OutboxMessage{}).Where("1 = 1").Update("locked_until", time.Now().Add(time.Minute))
+
+	mail.ProcessOutbox()
+	if len(sender.Messages()) != 0 {
+		t.Fatalf("\nSent A Message Another Worker Holds\n")
+	}
+
+	database.DB.Model(&mail.OutboxMessage{}).Where("1 = 1").Update("locked_until", time.Now().Add(-time.Second))
+	mail.ProcessOutbox()
+	if len(sender.Messages()) != 1 {
+		t.Fatalf("\nLapsed Claim Wasn't Picked Up\n")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	config.Set(config.Default())
+	base := time.Duration(config.Get().Mail.RetryBackoff) * time.Second
+
+	if mail.Backoff(1) != base || mail.Backoff(2) != 2*base || mail.Backoff(4) != 8*base {
+		t.Fatalf("\nBackoff Should Double: %s %s %s\n", mail.Backoff(1), mail.Backoff(2), mail.Backoff(4))
+	}
+	if mail.Backoff(100) != mail.MaxBackoff {
+		t.Fatalf("\nBackoff Should Be Capped: %s\n", mail.Backoff(100))
+	}
+}
@@ -0,0 +1,45 @@
+package mail
+
+import "sync"
+
+// Keeps Messages In Memory - For Tests
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+	// Returned From Send When Set, Nothing Is Kept
+	Err error
+}
+
+func (s *MemorySender) Send(msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Everything Sent So Far, Oldest First
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Sent Messages Addressed To to
+func (s *MemorySender) To(to string) []Message {
+	var found []Message
+	for _, msg := range s.Messages() {
+		if msg.To == to {
+			found = append(found, msg)
+		}
+	}
+	return found
+}
+
+func (s *MemorySender) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
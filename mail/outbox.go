@@ -0,0 +1,174 @@
+package mail
+
+import (
+	"context"
+	"time"
+
+	"app/config"
+	"app/database"
+	"app/logging"
+)
+
+/*
+Messages Waiting To Be Sent - Handlers Only Insert A Row So They Never
+Block On SMTP, The Worker Delivers And Retries With Exponential Backoff
+Bodies Are Cleared Once A Message Is Sent Or Given Up On As They Can
+Hold Single Use Links
+*/
+type OutboxMessage struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Recipient     string     `json:"recipient" gorm:"type:VARCHAR(254);not null"`
+	Subject       string     `json:"subject" gorm:"type:VARCHAR(255);not null"`
+	Text          string     `json:"-" gorm:"type:TEXT"`
+	HTML          string     `json:"-" gorm:"type:TEXT"`
+	Attempts      int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"not null;index:idx_mail_outbox_next_attempt_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	SentAt        *time.Time `json:"sent_at"`
+	FailedAt      *time.Time `json:"failed_at"` // Gave Up After mail.max_attempts
+	LastError     string     `json:"last_error" gorm:"type:VARCHAR(1024)"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "mail_outbox"
+}
+
+const (
+	// A Claimed Message Is Retried By Another Worker If Not Finished In Time
+	ClaimTimeout = 2 * time.Minute
+	BatchSize    = 20
+	// Backoff Never Grows Past This
+	MaxBackoff = 24 * time.Hour
+)
+
+// Adds A Message To The Outbox For The Worker To Send
+func Enqueue(ctx context.Context, msg Message) error {
+	return database.DB.WithContext(ctx).Create(&OutboxMessage{
+		Recipient:     msg.To,
+		Subject:       msg.Subject,
+		Text:          msg.Text,
+		HTML:          msg.HTML,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// Renders A Template (See Render) And Enqueues It
+func Queue(ctx context.Context, to string, name string, locale string, data interface{}) error {
+	msg, err := Render(to, name, locale, data)
+	if err != nil {
+		return err
+	}
+	return Enqueue(ctx, msg)
+}
+
+/*
+Sends Every Due Message, A Batch At A Time - Returns How Many Were Attempted
+Safe To Run On Several Instances, Each Message Is Claimed Before Sending
+*/
+func ProcessOutbox() (int, error) {
+	attempted := 0
+	for {
+		var due []OutboxMessage
+		now := time.Now()
+		err := database.DB.
+			Where("sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ?", now).
+			Where("locked_until IS NULL OR locked_until < ?", now).
+			Order("next_attempt_at").
+			Limit(BatchSize).
+			Find(&due).Error
+		if err != nil {
+			return attempted, err
+		}
+
+		claimed := 0
+		for i := range due {
+			if !claim(&due[i]) {
+				continue
+			}
+			claimed++
+			attempted++
+			deliver(&due[i])
+		}
+		if len(due) < BatchSize || claimed == 0 {
+			return attempted, nil
+		}
+	}
+}
+
+// Polls The Outbox Every mail.poll_interval Until ctx Is Done
+func RunWorker(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(config.Get().Mail.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		_, err := ProcessOutbox()
+		if err != nil {
+			logging.Get().Error("Mail Outbox Error", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Takes The Lease On A Message - False If Another Worker Got It First
+func claim(msg *OutboxMessage) bool {
+	now := time.Now()
+	lease := now.Add(ClaimTimeout)
+	res := database.DB.Model(&OutboxMessage{}).
+		Where("id = ? AND sent_at IS NULL AND failed_at IS NULL", msg.ID).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Update("locked_until", lease)
+	return res.Error == nil && res.RowsAffected == 1
+}
+
+func deliver(msg *OutboxMessage) {
+	err := Current().Send(Message{To: msg.Recipient, Subject: msg.Subject, Text: msg.Text, HTML: msg.HTML})
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"attempts":     msg.Attempts + 1,
+		"locked_until": nil,
+	}
+	switch {
+	case err == nil:
+		updates["sent_at"] = now
+		updates["text"], updates["html"] = "", ""
+	case msg.Attempts+1 >= config.Get().Mail.MaxAttempts:
+		updates["failed_at"] = now
+		updates["text"], updates["html"] = "", ""
+		updates["last_error"] = truncate(err.Error(), 1024)
+		logging.Get().Warn("Mail Outbox: Giving Up On Message", "message_id", msg.ID, "to", msg.Recipient, "error", err)
+	default:
+		updates["next_attempt_at"] = now.Add(Backoff(msg.Attempts + 1))
+		updates["last_error"] = truncate(err.Error(), 1024)
+	}
+
+	err = database.DB.Model(&OutboxMessage{}).Where("id = ?", msg.ID).Updates(updates).Error
+	if err != nil {
+		logging.Get().Error("Mail Outbox: Failed To Record Delivery", "message_id", msg.ID, "error", err)
+	}
+}
+
+// Wait Before Retrying After attempts Failures - retry_backoff Doubled Each Time
+func Backoff(attempts int) time.Duration {
+	wait := time.Duration(config.Get().Mail.RetryBackoff) * time.Second
+	for i := 1; i < attempts && wait < MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > MaxBackoff {
+		return MaxBackoff
+	}
+	return wait
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
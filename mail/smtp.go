@@ -0,0 +1,39 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+
+	"app/config"
+)
+
+type SMTPSender struct {
+	Host string // host:port
+	User string
+	Pass string
+	From string
+}
+
+func NewSMTPSender(cfg config.SMTPConfig, from string) *SMTPSender {
+	return &SMTPSender{Host: cfg.Host, User: cfg.User, Pass: cfg.Pass, From: from}
+}
+
+// STARTTLS Is Used Whenever The Server Offers It
+func (s *SMTPSender) Send(msg Message) error {
+	host, _, err := net.SplitHostPort(s.Host)
+	if err != nil {
+		return fmt.Errorf("smtp.host Must Be host:port: %w", err)
+	}
+
+	data, err := msg.Bytes(s.From)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if s.User != "" {
+		auth = smtp.PlainAuth("", s.User, s.Pass, host)
+	}
+	return smtp.SendMail(s.Host, auth, s.From, []string{msg.To}, data)
+}
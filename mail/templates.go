@@ -0,0 +1,101 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"app/config"
+)
+
+/*
+Templates Live In templates/<locale>/<name>.txt With An Optional <name>.html
+Next To It - The Text Template Defines "subject", HTML Templates Define
+"content" Which Is Wrapped In templates/layout.html
+*/
+//go:embed templates
+var templates embed.FS
+
+// Locales With At Least One Template - For Matching Accept-Language
+func Locales() []string {
+	entries, _ := fs.ReadDir(templates, "templates")
+	var locales []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			locales = append(locales, entry.Name())
+		}
+	}
+	return locales
+}
+
+// Renders A Template Into A Message For to, Falling Back To The Default Locale
+func Render(to string, name string, locale string, data interface{}) (Message, error) {
+	dir, err := resolveLocale(name, locale)
+	if err != nil {
+		return Message{}, err
+	}
+	msg := Message{To: to}
+
+	text, err := texttemplate.ParseFS(templates, path.Join(dir, name+".txt"))
+	if err != nil {
+		return msg, err
+	}
+	if text.Lookup("subject") == nil {
+		return msg, fmt.Errorf("Mail Template %s/%s.txt Doesn't Define A Subject", dir, name)
+	}
+	msg.Subject, err = execute(text, "subject", data)
+	if err != nil {
+		return msg, err
+	}
+	msg.Subject = strings.TrimSpace(msg.Subject)
+	msg.Text, err = execute(text, path.Base(name+".txt"), data)
+	if err != nil {
+		return msg, err
+	}
+	msg.Text = strings.TrimSpace(msg.Text) + "\n"
+
+	htmlPath := path.Join(dir, name+".html")
+	if _, err := fs.Stat(templates, htmlPath); err != nil {
+		return msg, nil
+	}
+	html, err := htmltemplate.ParseFS(templates, "templates/layout.html", htmlPath)
+	if err != nil {
+		return msg, err
+	}
+	msg.HTML, err = execute(html, "layout", data)
+	return msg, err
+}
+
+// Satisfied By Both text/template And html/template
+type templateSet interface {
+	ExecuteTemplate(w io.Writer, name string, data interface{}) error
+}
+
+func execute(tmpl templateSet, name string, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	err := tmpl.ExecuteTemplate(&buf, name, data)
+	return buf.String(), err
+}
+
+// Tries "pt-BR", Then "pt", Then mail.default_locale, Then "en"
+func resolveLocale(name string, locale string) (string, error) {
+	locale = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	base, _, _ := strings.Cut(locale, "-")
+
+	for _, candidate := range []string{locale, base, strings.ToLower(config.Get().Mail.DefaultLocale), "en"} {
+		if candidate == "" {
+			continue
+		}
+		dir := path.Join("templates", candidate)
+		if _, err := fs.Stat(templates, path.Join(dir, name+".txt")); err == nil {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("No Mail Template Named %q", name)
+}
@@ -1,7 +1,57 @@
 package main
 
-import "app/server"
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"app/config"
+	"app/server"
+)
 
 func main() {
-	server.Start()
+	configFile := flag.String("config", os.Getenv("APP_CONFIG_FILE"), "Path To A YAML, TOML Or JSON Config File")
+	flag.Usage = usage
+	flag.Parse()
+
+	// migrate create Doesn't Need A Valid Config
+	if flag.Arg(0) == "migrate" && flag.Arg(1) == "create" {
+		createMigration(flag.Args()[2:])
+		return
+	}
+
+	// Load Config Or Die
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatalf("Invalid Config:\n%v", err)
+	}
+
+	switch flag.Arg(0) {
+	case "", "serve":
+		server.Start(cfg)
+	case "migrate":
+		migrate(cfg, flag.Args()[1:])
+	case "audit":
+		auditCommand(cfg, flag.Args()[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: %s [-config file] [command]
+
+Commands:
+  serve                      Start The API (Default)
+  migrate up                 Apply Every Pending Migration
+  migrate down [steps]       Roll Back The Last steps Migrations (Default 1)
+  migrate status             List Migrations And Whether They're Applied
+  migrate create <name>      Write An Empty Migration To database/migrations
+  audit verify               Check The Audit Log's Hash Chain And Signed Checkpoints
+
+Flags:
+`, os.Args[0])
+	flag.PrintDefaults()
 }
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+
+	"app/database"
+)
+
+/*
+	Database
+*/
+
+var (
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "GORM statement latency by operation.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation"})
+
+	// Not Found Isn't Counted - Handlers Expect It
+	queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "GORM statements that failed by operation.",
+	}, []string{"operation"})
+)
+
+// The Database The Pool Stats Come From - Replaced By Each InstrumentDB
+var instrumented atomic.Pointer[gorm.DB]
+
+// Times Every Statement On db And Reports Its Connection Pool
+func InstrumentDB(db *gorm.DB) error {
+	err := db.Use(gormPlugin{})
+	if err != nil {
+		return err
+	}
+	instrumented.Store(db)
+	return nil
+}
+
+const startKey = "metrics:start"
+
+type gormPlugin struct{}
+
+func (gormPlugin) Name() string {
+	return "metrics"
+}
+
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	return database.Hook(db, "metrics", startTimer, observe)
+}
+
+func startTimer(string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		db.InstanceSet(startKey, time.Now())
+	}
+}
+
+func observe(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		queryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			queryErrors.WithLabelValues(operation).Inc()
+		}
+	}
+}
+
+/*
+	Connection Pool
+*/
+
+var (
+	poolMaxOpen = prometheus.NewDesc("db_pool_max_open_connections", "Most connections the pool will open.", nil, nil)
+	poolOpen    = prometheus.NewDesc("db_pool_open_connections", "Connections open, in use or idle.", nil, nil)
+	poolInUse   = prometheus.NewDesc("db_pool_in_use_connections", "Connections in use.", nil, nil)
+	poolIdle    = prometheus.NewDesc("db_pool_idle_connections", "Idle connections.", nil, nil)
+	poolWaits   = prometheus.NewDesc("db_pool_wait_count_total", "Times a caller waited for a connection.", nil, nil)
+	poolWaited  = prometheus.NewDesc("db_pool_wait_duration_seconds_total", "Time spent waiting for a connection.", nil, nil)
+	poolClosed  = prometheus.NewDesc("db_pool_closed_total", "Connections closed by the pool by reason.", []string{"reason"}, nil)
+)
+
+// Reads database/sql Stats At Scrape Time
+type poolCollector struct{}
+
+func (poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{poolMaxOpen, poolOpen, poolInUse, poolIdle, poolWaits, poolWaited, poolClosed} {
+		ch <- desc
+	}
+}
+
+func (poolCollector) Collect(ch chan<- prometheus.Metric) {
+	db := instrumented.Load()
+	if db == nil {
+		return
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return
+	}
+	stats := sqlDB.Stats()
+
+	ch <- prometheus.MustNewConstMetric(poolMaxOpen, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(poolOpen, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(poolInUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(poolIdle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(poolWaits, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(poolWaited, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(poolClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed), "max_idle")
+	ch <- prometheus.MustNewConstMetric(poolClosed, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed), "max_idle_time")
+	ch <- prometheus.MustNewConstMetric(poolClosed, prometheus.CounterValue, float64(stats.MaxLifetimeClosed), "max_lifetime")
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"app/util"
+)
+
+/*
+	HTTP
+*/
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests being served.",
+	})
+)
+
+// Route Label For Requests No Route Matched - Keeps Scanners From Adding A Series Per Path
+const unmatchedRoute = "unmatched"
+
+/*
+Counts And Times Every Request Under Its Route Pattern (/user/:id, Not /user/7)
+Goes Outside logging.AccessLog, Which Settles The Status Of Returned Errors
+*/
+func HTTP() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		err := c.Next()
+
+		route, matched := util.MatchedRoute(c)
+		if !matched {
+			route = unmatchedRoute
+		}
+		// Labels Outlive The Request - Fiber Reuses The Buffer Behind c.Method
+		method := utils.CopyString(c.Method())
+		status := strconv.Itoa(c.Response().StatusCode())
+		httpRequests.WithLabelValues(method, route, status).Inc()
+		httpDuration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Prometheus Metrics - HTTP Traffic, Logins, Tokens, Password Hashing And
+The Database. Everything Is Registered With One Registry Served By Handler
+*/
+package metrics
+
+import (
+	"crypto/subtle"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"app/config"
+)
+
+// Token Types For TokensIssued And TokenValidations
+const (
+	TokenAccess         = "access"
+	TokenRefresh        = "refresh"
+	TokenPersonalAccess = "personal_access"
+)
+
+// Password Hashing Operations For PasswordHashDuration
+const (
+	PasswordHash    = "hash"
+	PasswordCompare = "compare"
+)
+
+var registry = prometheus.NewRegistry()
+
+/*
+	Auth
+*/
+
+var (
+	// Successful Logins - method Is password, mfa Or passkey
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_total",
+		Help: "Successful logins by method.",
+	}, []string{"method"})
+
+	// Failed Logins - reason Matches The Audit Log
+	LoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_login_failures_total",
+		Help: "Failed logins by reason.",
+	}, []string{"reason"})
+
+	// Attempts Turned Away Before The Password Was Checked - reason Is throttled Or locked
+	LoginsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_logins_rejected_total",
+		Help: "Login attempts refused by failed login throttling.",
+	}, []string{"reason"})
+
+	AccountLockouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "auth_account_lockouts_total",
+		Help: "Accounts locked after repeated failed logins.",
+	})
+
+	TokensIssued = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_tokens_issued_total",
+		Help: "Access, refresh and personal access tokens issued.",
+	}, []string{"type"})
+
+	// result Is valid Or Why The Token Was Refused
+	TokenValidations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_validations_total",
+		Help: "Tokens presented by type and result.",
+	}, []string{"type", "result"})
+
+	// bcrypt Is Deliberately Slow - Watch It Doesn't Become The Bottleneck
+	PasswordHashDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "auth_password_hash_duration_seconds",
+		Help:    "Time spent hashing and comparing passwords.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation"})
+)
+
+/*
+	Audit
+*/
+
+// Anything Above Zero Means The Audit Log Is Missing Events - Alert On It
+var AuditEventsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "audit_events_dropped_total",
+	Help: "Audit events that could not be recorded, by event type.",
+}, []string{"type"})
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, httpInFlight,
+		Logins, LoginFailures, LoginsRejected, AccountLockouts,
+		TokensIssued, TokenValidations, PasswordHashDuration,
+		AuditEventsDropped,
+		queryDuration, queryErrors, poolCollector{},
+	)
+}
+
+/*
+Serves Every Metric In Prometheus Text Format - With metrics.token Set
+Anything Without It As A Bearer Token Gets A 401
+*/
+func Handler(cfg config.MetricsConfig) fiber.Handler {
+	serve := adaptor.HTTPHandler(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	return func(c *fiber.Ctx) error {
+		if cfg.Token != "" {
+			expected := "Bearer " + cfg.Token
+			if subtle.ConstantTimeCompare([]byte(c.Get(fiber.HeaderAuthorization)), []byte(expected)) != 1 {
+				c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="metrics"`)
+				return c.Status(401).JSON(fiber.Map{"error": "Invalid Metrics Token"})
+			}
+		}
+		return serve(c)
+	}
+}
@@ -0,0 +1,102 @@
+package metrics_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
+
+	"app/metrics"
+	"app/testutil"
+)
+
+func metricsApp(t *testing.T, token string) *fiber.App {
+	cfg := testutil.Config(t)
+	cfg.Metrics.Enabled = true
+	cfg.Metrics.Token = token
+	return testutil.SetupWith(t, cfg)
+}
+
+func scrape(t *testing.T, body []byte, series ...string) {
+	t.Helper()
+	for _, s := range series {
+		if !strings.Contains(string(body), s) {
+			t.Fatalf("\nMissing Series: %s\n", s)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	app := metricsApp(t, "")
+	testutil.CreateUser(t, "jane", "123", "default")
+	testutil.Login(t, app, "jane", "123")
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/login", map[string]string{"username": "jane", "password": "wrong"}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+	res, body = testutil.Request(t, app, http.MethodGet, "/no/such/route", nil, "")
+	testutil.ExpectStatus(t, res, body, 404)
+
+	res, body = testutil.Request(t, app, http.MethodGet, "/metrics", nil, "")
+	testutil.ExpectStatus(t, res, body, 200)
+	scrape(t, body,
+		`http_requests_total{method="POST",route="/user/login",status="200"}`,
+		`http_requests_total{method="POST",route="/user/login",status="400"}`,
+		`http_requests_total{method="GET",route="unmatched",status="404"}`,
+		`http_request_duration_seconds_bucket{method="POST",route="/user/login",status="200",le=`,
+		`auth_logins_total{method="password"}`,
+		`auth_login_failures_total{reason="password"}`,
+		`auth_tokens_issued_total{type="access"}`,
+		`auth_tokens_issued_total{type="refresh"}`,
+		`auth_password_hash_duration_seconds_count{operation="hash"}`,
+		`auth_password_hash_duration_seconds_count{operation="compare"}`,
+		`db_query_duration_seconds_count{operation="query"}`,
+		`db_query_duration_seconds_count{operation="create"}`,
+		`db_pool_open_connections`,
+		`go_goroutines`,
+	)
+	if strings.Contains(string(body), "/no/such/route") {
+		t.Fatalf("\nUnmatched Path Became A Label\n")
+	}
+}
+
+func TestTokenValidations(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "jane", "123", "default")
+	authorization := testutil.Login(t, app, "jane", "123")
+
+	valid := metrics.TokenValidations.WithLabelValues(metrics.TokenAccess, "valid")
+	malformed := metrics.TokenValidations.WithLabelValues(metrics.TokenAccess, "malformed")
+	unknown := metrics.TokenValidations.WithLabelValues(metrics.TokenPersonalAccess, "unknown")
+	before := []float64{promtest.ToFloat64(valid), promtest.ToFloat64(malformed), promtest.ToFloat64(unknown)}
+
+	res, body := testutil.Request(t, app, http.MethodGet, "/user/", nil, authorization)
+	testutil.ExpectStatus(t, res, body, 200)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer nonsense")
+	testutil.ExpectStatus(t, res, body, 401)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer uap_0123abcd_nonsense")
+	testutil.ExpectStatus(t, res, body, 401)
+
+	after := []float64{promtest.ToFloat64(valid), promtest.ToFloat64(malformed), promtest.ToFloat64(unknown)}
+	for i := range before {
+		if after[i] != before[i]+1 {
+			t.Fatalf("\nUnexpected Validation Counts: %v Before: %v\n", after, before)
+		}
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	app := metricsApp(t, "scrape-secret")
+
+	res, body := testutil.Request(t, app, http.MethodGet, "/metrics", nil, "")
+	testutil.ExpectStatus(t, res, body, 401)
+	res, body = testutil.Request(t, app, http.MethodGet, "/metrics", nil, "Bearer wrong")
+	testutil.ExpectStatus(t, res, body, 401)
+	res, body = testutil.Request(t, app, http.MethodGet, "/metrics", nil, "Bearer scrape-secret")
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Off Unless Asked For
+	app = testutil.Setup(t)
+	res, body = testutil.Request(t, app, http.MethodGet, "/metrics", nil, "")
+	testutil.ExpectStatus(t, res, body, 404)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"app/config"
+	"app/database"
+	"app/database/migrations"
+)
+
+func migrate(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	db, err := database.Open(cfg.DB)
+	if err != nil {
+		log.Fatalf("Failed To Connect To Database: %v", err)
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := migrations.Up(db)
+		for _, m := range ran {
+			fmt.Printf("Applied %s_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(ran) == 0 {
+			fmt.Println("Nothing To Migrate")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("Invalid Steps: %s", args[1])
+			}
+		}
+		ran, err := migrations.Down(db, steps)
+		for _, m := range ran {
+			fmt.Printf("Rolled Back %s_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		statuses, err := migrations.GetStatus(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			state := "Pending"
+			if s.AppliedAt != nil {
+				state = "Applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Missing {
+				state += " (Missing From Source)"
+			}
+			fmt.Printf("%s_%-40s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func createMigration(args []string) {
+	fs := flag.NewFlagSet("migrate create", flag.ExitOnError)
+	dir := fs.String("dir", "database/migrations", "Directory To Write The Migration To")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		log.Fatal("Usage: migrate create [-dir directory] <name>")
+	}
+
+	path, err := migrations.Create(*dir, fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed To Create Migration: %v", err)
+	}
+	fmt.Printf("Created %s\n", path)
+}
@@ -0,0 +1,139 @@
+package user
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"app/config"
+	"app/database"
+	"app/logging"
+)
+
+/*
+A Role Held On Top Of The User's Primary Role - Nil ExpiresAt Never Expires
+Expired Grants Stop Counting Straight Away And Are Swept Up Later
+*/
+type UserRoleGrant struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UserID     uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_user_role_grant"`
+	UserRoleID uint       `json:"role_id" gorm:"not null;uniqueIndex:idx_user_role_grant;index"`
+	Role       UserRole   `json:"role" gorm:"foreignKey:UserRoleID"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"index"`
+	GrantedBy  uint       `json:"granted_by"`
+}
+
+type RoleGrantRequest struct {
+	RoleID    uint       `json:"role_id" validate:"required,number"`
+	ExpiresAt *time.Time `json:"expires_at" validate:"omitempty"`
+}
+
+// Grants That Still Count At now
+func activeGrants(tx *gorm.DB, now time.Time) *gorm.DB {
+	return tx.Where("user_role_grants.expires_at IS NULL OR user_role_grants.expires_at > ?", now)
+}
+
+// Names Of The User's Primary Role Then Every Active Grant
+func RoleNames(ctx context.Context, userID uint) ([]string, error) {
+	var primary []string
+	err := database.DB.WithContext(ctx).Table("user_roles").
+		Joins("JOIN users ON users.role_id = user_roles.id").
+		Where("users.id = ?", userID).
+		Pluck("user_roles.role", &primary).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var granted []string
+	err = activeGrants(database.DB.WithContext(ctx).Table("user_roles"), time.Now()).
+		Joins("JOIN user_role_grants ON user_role_grants.user_role_id = user_roles.id").
+		Where("user_role_grants.user_id = ? AND user_roles.deleted_at IS NULL", userID).
+		Order("user_role_grants.id").
+		Pluck("user_roles.role", &granted).Error
+	if err != nil {
+		return nil, err
+	}
+
+	names := primary
+	for _, name := range granted {
+		if !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// Adds Or Re-Dates Grants Then Drops The Removed Ones
+func changeRoleGrants(tx *gorm.DB, userID uint, grantedBy uint, add []RoleGrantRequest, remove []uint) error {
+	for _, r := range add {
+		grant := UserRoleGrant{UserID: userID, UserRoleID: r.RoleID, ExpiresAt: r.ExpiresAt, GrantedBy: grantedBy}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "user_role_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"expires_at", "granted_by"}),
+		}).Create(&grant).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(remove) != 0 {
+		err := tx.Where("user_id = ? AND user_role_id IN ?", userID, remove).Delete(&UserRoleGrant{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Each Grant's Role And Expiry In Role Order - What Audit Events Compare
+func auditGrants(grants []UserRoleGrant) []fiber.Map {
+	sorted := append([]UserRoleGrant(nil), grants...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].UserRoleID < sorted[j].UserRoleID })
+
+	summary := make([]fiber.Map, len(sorted))
+	for i, grant := range sorted {
+		summary[i] = fiber.Map{"role_id": grant.UserRoleID, "expires_at": grant.ExpiresAt}
+	}
+	return summary
+}
+
+/*
+	Sweeper
+*/
+
+// Deletes Expired Grants - Returns How Many Went
+func ExpireRoleGrants() (int64, error) {
+	res := database.DB.Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now()).Delete(&UserRoleGrant{})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected != 0 {
+		ClearPermissionCache()
+	}
+	return res.RowsAffected, nil
+}
+
+// Sweeps Every auth.role_grant_sweep_interval Until ctx Is Done
+func RunRoleGrantSweeper(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(config.Get().Auth.RoleGrantSweepInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		expired, err := ExpireRoleGrants()
+		if err != nil {
+			logging.Get().Error("Role Grant Sweep Error", "error", err)
+		}
+		if expired != 0 {
+			logging.Get().Debug("Expired Role Grants", "count", expired)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
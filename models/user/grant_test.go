@@ -0,0 +1,109 @@
+package user_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"app/api/auth"
+	"app/config"
+	"app/database"
+	"app/models/user"
+	"app/testutil"
+)
+
+func TestRoleGrants(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "admin", "123", "admin")
+	tester := testutil.CreateUser(t, "tester", "123", "default")
+	adminToken := testutil.Login(t, app, "admin", "123")
+	admin := testutil.Role(t, "admin")
+
+	enabled := true
+	expires := time.Now().Add(time.Hour)
+	req := user.AdminUserUpdateRequest{
+		UserID:          tester.ID,
+		RoleID:          user.DefaultRoleID,
+		Email:           tester.Email,
+		Account_enabled: &enabled,
+		AddRoles:        []user.RoleGrantRequest{{RoleID: admin.ID, ExpiresAt: &expires}},
+	}
+	res, body := testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, adminToken)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	updated := new(UserResponse)
+	testutil.Decode(t, body, updated)
+	if len(updated.User.RoleGrants) != 1 || updated.User.RoleGrants[0].Role.Role != "admin" {
+		t.Fatalf("\nGrant Missing From Response: %+v\n", updated.User.RoleGrants)
+	}
+
+	// Tokens List Every Active Role With The Primary First
+	token := testutil.Login(t, app, "tester", "123")
+	claims, err := auth.ParseToken(strings.TrimPrefix(token, "Bearer "), config.Get().Auth.Audience)
+	if err != nil {
+		t.Fatalf("\nFailed To Parse Token: %s\n", err.Error())
+	}
+	if claims.Role != "default" || strings.Join(claims.Roles, ",") != "default,admin" {
+		t.Fatalf("\nUnexpected Roles: %s %v\n", claims.Role, claims.Roles)
+	}
+
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/getall", nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Granted Roles Can't Be Deleted Either
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/roles", user.CreateRoleRequest{Role: "billing"}, adminToken)
+	testutil.ExpectStatus(t, res, body, 201)
+	billing := testutil.Role(t, "billing")
+	req.AddRoles = []user.RoleGrantRequest{{RoleID: billing.ID}}
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, adminToken)
+	testutil.ExpectStatus(t, res, body, 200)
+	res, body = testutil.Request(t, app, http.MethodDelete, fmt.Sprintf("/user/roles/%d", billing.ID), nil, adminToken)
+	testutil.ExpectStatus(t, res, body, 409)
+
+	// Removing Applies Straight Away
+	req.AddRoles = nil
+	req.RemoveRoles = []uint{admin.ID, billing.ID}
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, adminToken)
+	testutil.ExpectStatus(t, res, body, 200)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/getall", nil, token)
+	testutil.ExpectStatus(t, res, body, 403)
+
+	// Grants Must Expire In The Future And Name A Real Role
+	past := time.Now().Add(-time.Minute)
+	req.RemoveRoles = nil
+	req.AddRoles = []user.RoleGrantRequest{{RoleID: admin.ID, ExpiresAt: &past}}
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, adminToken)
+	testutil.ExpectStatus(t, res, body, 400)
+	req.AddRoles = []user.RoleGrantRequest{{RoleID: 999}}
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, adminToken)
+	testutil.ExpectStatus(t, res, body, 404)
+}
+
+// Cached Permissions Don't Outlive The Grant They Came From
+func TestRoleGrantExpiry(t *testing.T) {
+	app := testutil.Setup(t)
+	tester := testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+
+	expires := time.Now().Add(500 * time.Millisecond)
+	grant := user.UserRoleGrant{UserID: tester.ID, UserRoleID: testutil.Role(t, "admin").ID, ExpiresAt: &expires}
+	err := database.DB.Create(&grant).Error
+	if err != nil {
+		t.Fatalf("\nFailed To Grant Role: %s\n", err.Error())
+	}
+
+	res, body := testutil.Request(t, app, http.MethodGet, "/user/getall", nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	time.Sleep(time.Until(expires) + 50*time.Millisecond)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/getall", nil, token)
+	testutil.ExpectStatus(t, res, body, 403)
+
+	// The Sweeper Clears It Out
+	expired, err := user.ExpireRoleGrants()
+	if err != nil || expired != 1 {
+		t.Fatalf("\nExpired %d Grant(s): %v Expected: 1\n", expired, err)
+	}
+}
@@ -0,0 +1,401 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"app/api/auth"
+	"app/audit"
+	"app/config"
+	"app/database"
+	"app/logging"
+	"app/util"
+)
+
+/*
+TOTP Second Factor - Enrolment Stays Unconfirmed Until A Code From The
+Authenticator Is Checked, Only Then Is It Required At Login
+*/
+type TOTPFactor struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UserID      uint       `json:"user_id" gorm:"not null;uniqueIndex"`
+	Secret      string     `json:"-" gorm:"type:VARCHAR(64);not null"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	// Codes Can't Be Used Twice - Anything At Or Before This Step Is Refused
+	LastUsedStep int64 `json:"-" gorm:"not null;default:0"`
+}
+
+// One Time Codes For When The Authenticator Is Lost - Only A SHA-256 Hash Is Stored
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"type:VARCHAR(64);not null;uniqueIndex"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+const RecoveryCodeCount = 10
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required,min=6,max=32"`
+}
+
+// Starts Enrolment - Returns The Secret And An otpauth:// URI To Show As A QR Code
+func EnrollTOTP(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		logging.For(c).Error("Enroll TOTP Error: Failed Parsing Uint", "error", err)
+		return c.SendStatus(500)
+	}
+
+	var user User
+	err = database.For(c).First(&user, user_id).Error
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "User Doesn't Exist"})
+	}
+
+	var existing TOTPFactor
+	err = database.For(c).Where("user_id = ?", user.ID).First(&existing).Error
+	if err == nil && existing.ConfirmedAt != nil {
+		return c.Status(409).JSON(fiber.Map{"error": "Two-Factor Authentication Is Already Enabled"})
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return c.SendStatus(500)
+	}
+
+	// Starting Over Replaces An Unconfirmed Enrolment
+	err = database.For(c).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND confirmed_at IS NULL", user.ID).Delete(&TOTPFactor{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&TOTPFactor{UserID: user.ID, Secret: secret}).Error
+	})
+	if err != nil {
+		logging.For(c).Error("Enroll TOTP Error", "error", err)
+		return c.SendStatus(500)
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"secret": secret,
+		"uri":    auth.TOTPURI(config.Get().Auth.Issuer, user.Username, secret),
+	})
+}
+
+// Confirms Enrolment With A Code - Returns Recovery Codes, Shown Only This Once
+func ConfirmTOTP(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		logging.For(c).Error("Confirm TOTP Error: Failed Parsing Uint", "error", err)
+		return c.SendStatus(500)
+	}
+
+	r := new(MFACodeRequest)
+	err = c.BodyParser(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	var factor TOTPFactor
+	err = database.For(c).Where("user_id = ? AND confirmed_at IS NULL", user_id).First(&factor).Error
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "No Pending Enrolment"})
+	}
+
+	ok, err := useTOTP(c.UserContext(), &factor, r.Code)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Code"})
+	}
+
+	now := time.Now()
+	err = database.For(c).Model(&factor).Update("confirmed_at", now).Error
+	if err != nil {
+		return c.SendStatus(500)
+	}
+
+	codes, err := replaceRecoveryCodes(c.UserContext(), uint(user_id))
+	if err != nil {
+		logging.For(c).Error("Confirm TOTP Error", "error", err)
+		return c.SendStatus(500)
+	}
+	audit.Record(c, audit.Entry{Type: audit.MFAEnabled, TargetType: audit.TargetUser, TargetID: uint(user_id)})
+	return c.Status(200).JSON(fiber.Map{"recovery_codes": codes})
+}
+
+// Turns Two-Factor Off - Needs A Current Code Or A Recovery Code
+func DisableTOTP(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		logging.For(c).Error("Disable TOTP Error: Failed Parsing Uint", "error", err)
+		return c.SendStatus(500)
+	}
+
+	r := new(MFACodeRequest)
+	err = c.BodyParser(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	ok, err := verifySecondFactor(c.UserContext(), uint(user_id), r.Code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Two-Factor Authentication Isn't Enabled"})
+	}
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Code"})
+	}
+
+	err = database.For(c).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", user_id).Delete(&TOTPFactor{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user_id).Delete(&RecoveryCode{}).Error
+	})
+	if err != nil {
+		logging.For(c).Error("Disable TOTP Error", "error", err)
+		return c.SendStatus(500)
+	}
+	audit.Record(c, audit.Entry{Type: audit.MFADisabled, TargetType: audit.TargetUser, TargetID: uint(user_id)})
+	return c.SendStatus(200)
+}
+
+// Replaces Every Recovery Code - Needs A Current Code Or An Unused Recovery Code
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		logging.For(c).Error("Regenerate Recovery Codes Error: Failed Parsing Uint", "error", err)
+		return c.SendStatus(500)
+	}
+
+	r := new(MFACodeRequest)
+	err = c.BodyParser(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	ok, err := verifySecondFactor(c.UserContext(), uint(user_id), r.Code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": "Two-Factor Authentication Isn't Enabled"})
+	}
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Code"})
+	}
+
+	codes, err := replaceRecoveryCodes(c.UserContext(), uint(user_id))
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	return c.Status(200).JSON(fiber.Map{"recovery_codes": codes})
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required,min=6,max=32"`
+}
+
+// Second Half Of Login - Exchanges The Challenge From Login And A Code For Tokens
+func LoginMFA(c *fiber.Ctx) error {
+	r := new(LoginMFARequest)
+	err := c.BodyParser(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	claims, err := auth.ParseToken(r.MFAToken, auth.AudienceMFAChallenge)
+	if errors.Is(err, auth.ErrKeysUnavailable) {
+		logging.For(c).Error("Login MFA Error", "error", err)
+		return c.SendStatus(500)
+	}
+	if err != nil {
+		return auth.Unauthorized(c, err)
+	}
+	user_id, err := claims.UserID()
+	if err != nil {
+		return auth.Unauthorized(c, err)
+	}
+	revoked, err := auth.IsRevoked(c.UserContext(), claims.ID, user_id, claims.Generation)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	if revoked {
+		return auth.Unauthorized(c, auth.ErrTokenRevoked)
+	}
+
+	var user User
+	err = database.For(c).Preload("Role").First(&user, user_id).Error
+	if err != nil {
+		return auth.Unauthorized(c, auth.ErrTokenClaims)
+	}
+	if !*user.AccountEnabled {
+		return c.Status(403).JSON(fiber.Map{"error": "User Account Disabled"})
+	}
+	// Codes Are Throttled Like Passwords
+	blocked, err := loginThrottle(c.UserContext(), addressTarget(c.IP()), accountTarget(user.ID))
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	if blocked != nil {
+		return throttled(c, blocked)
+	}
+
+	ok, err := verifySecondFactor(c.UserContext(), user.ID, r.Code)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.SendStatus(500)
+	}
+	if !ok {
+		logging.For(c).Info("Failed MFA Attempt", "username", user.Username)
+		loginFailed(c, user.Username, &user, "mfa")
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Code"})
+	}
+
+	// Challenges Are Single Use - Tokens Aren't Issued Unless It's Spent
+	err = auth.RevokeToken(c.UserContext(), claims.ID, user.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		logging.For(c).Error("Login MFA Revoke Error", "error", err)
+		return c.SendStatus(500)
+	}
+
+	token, refreshToken, err := issueTokens(c.UserContext(), &user)
+	if err != nil {
+		logging.For(c).Error("Login MFA JWT Error", "error", err)
+		return c.SendStatus(500)
+	}
+	clearLoginFailures(c.UserContext(), user.ID)
+	auditLogin(c, &user, "mfa")
+	user.Password = ""
+	return c.Status(200).JSON(fiber.Map{"token": token, "refresh_token": refreshToken, "user": user})
+}
+
+// Whether Login Needs A Second Factor
+func mfaEnabled(ctx context.Context, userID uint) (bool, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&TOTPFactor{}).Where("user_id = ? AND confirmed_at IS NOT NULL", userID).Count(&count).Error
+	return count > 0, err
+}
+
+// Short Lived Token Proving The Password Was Checked - Only Accepted By LoginMFA
+func issueMFAChallenge(ctx context.Context, user *User) (string, error) {
+	ttl := time.Duration(config.Get().Auth.MFAChallengeExpires) * time.Second
+	claims, err := auth.NewClaims(user.ID, auth.AudienceMFAChallenge, ttl)
+	if err != nil {
+		return "", err
+	}
+	// A Password Reset In Between Cancels The Challenge
+	claims.Generation, err = auth.CurrentGeneration(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	keys, err := auth.Keys()
+	if err != nil {
+		return "", err
+	}
+	return keys.Sign(claims)
+}
+
+// Accepts A TOTP Code Or An Unused Recovery Code - ErrRecordNotFound If Not Enrolled
+func verifySecondFactor(ctx context.Context, userID uint, code string) (bool, error) {
+	var factor TOTPFactor
+	err := database.DB.WithContext(ctx).Where("user_id = ? AND confirmed_at IS NOT NULL", userID).First(&factor).Error
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := useTOTP(ctx, &factor, code)
+	if ok || err != nil {
+		return ok, err
+	}
+	return useRecoveryCode(ctx, userID, code)
+}
+
+// Checks A Code And Records Its Step So It Can't Be Replayed
+func useTOTP(ctx context.Context, factor *TOTPFactor, code string) (bool, error) {
+	step, ok := auth.ValidateTOTP(factor.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	res := database.DB.WithContext(ctx).Model(&TOTPFactor{}).
+		Where("id = ? AND last_used_step < ?", factor.ID, step).
+		Update("last_used_step", step)
+	return res.RowsAffected == 1, res.Error
+}
+
+func useRecoveryCode(ctx context.Context, userID uint, code string) (bool, error) {
+	res := database.DB.WithContext(ctx).Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, auth.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+// Issues A Fresh Set, Dropping Any Old Ones - Returns The Plain Codes
+func replaceRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	rows := make([]RecoveryCode, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, err
+		}
+		// 16 Base32 Characters - Shown As xxxx-xxxx-xxxx-xxxx
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes[i] = raw[:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:]
+		rows[i] = RecoveryCode{UserID: userID, CodeHash: auth.HashToken(raw)}
+	}
+
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,158 @@
+package user_test
+
+import (
+	"encoding/base32"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/api/auth"
+	"app/models/user"
+	"app/testutil"
+)
+
+type EnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	Token       string `json:"token"`
+}
+
+// Code For The Step offset Steps From Now
+func totpCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatalf("\nInvalid Secret: %s\n", err.Error())
+	}
+	return auth.HOTP(key, uint64(auth.TOTPStep(time.Now())+offset), auth.TOTPDigits)
+}
+
+// Enrolls And Confirms With The Current Step - Returns The Secret And Recovery Codes
+func enrollTOTP(t *testing.T, app *fiber.App, token string) (string, []string) {
+	t.Helper()
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/mfa/totp/enroll", nil, token)
+	testutil.ExpectStatus(t, res, body, 201)
+	enrolled := new(EnrollResponse)
+	testutil.Decode(t, body, enrolled)
+
+	confirmCode := totpCode(t, enrolled.Secret, 0)
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/mfa/totp/confirm", user.MFACodeRequest{Code: confirmCode}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	codes := new(RecoveryCodesResponse)
+	testutil.Decode(t, body, codes)
+	return enrolled.Secret, codes.RecoveryCodes
+}
+
+func loginChallenge(t *testing.T, app *fiber.App) string {
+	t.Helper()
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+	challenge := new(MFAChallengeResponse)
+	testutil.Decode(t, body, challenge)
+	if !challenge.MFARequired || challenge.MFAToken == "" || challenge.Token != "" {
+		t.Fatalf("\nExpected An MFA Challenge Instead Of Tokens: %s\n", body)
+	}
+	return challenge.MFAToken
+}
+
+func TestTOTPLogin(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/mfa/totp/enroll", nil, token)
+	testutil.ExpectStatus(t, res, body, 201)
+	enrolled := new(EnrollResponse)
+	testutil.Decode(t, body, enrolled)
+	if enrolled.Secret == "" || enrolled.URI == "" {
+		t.Fatalf("\nMissing Provisioning Details: %s\n", body)
+	}
+
+	// Unconfirmed Enrolment Doesn't Change Login
+	testutil.Login(t, app, "tester", "123")
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/mfa/totp/confirm", user.MFACodeRequest{Code: "000000"}, token)
+	testutil.ExpectStatus(t, res, body, 400)
+	confirmCode := totpCode(t, enrolled.Secret, 0)
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/mfa/totp/confirm", user.MFACodeRequest{Code: confirmCode}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	codes := new(RecoveryCodesResponse)
+	testutil.Decode(t, body, codes)
+	if len(codes.RecoveryCodes) != user.RecoveryCodeCount {
+		t.Fatalf("\nExpected %d Recovery Codes: %s\n", user.RecoveryCodeCount, body)
+	}
+
+	// Enrolling Twice Is Refused
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/mfa/totp/enroll", nil, token)
+	testutil.ExpectStatus(t, res, body, 409)
+
+	challenge := loginChallenge(t, app)
+
+	// The Challenge Isn't An Access Token
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer "+challenge)
+	testutil.ExpectStatus(t, res, body, 401)
+
+	// The Code Used To Confirm Can't Be Replayed
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login/mfa", user.LoginMFARequest{MFAToken: challenge, Code: confirmCode}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login/mfa", user.LoginMFARequest{MFAToken: challenge, Code: totpCode(t, enrolled.Secret, 1)}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+	tokens := new(TokenResponse)
+	testutil.Decode(t, body, tokens)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer "+tokens.Token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Challenges Are Single Use
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login/mfa", user.LoginMFARequest{MFAToken: challenge, Code: codes.RecoveryCodes[0]}, "")
+	testutil.ExpectStatus(t, res, body, 401)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+	_, codes := enrollTOTP(t, app, token)
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/login/mfa", user.LoginMFARequest{MFAToken: loginChallenge(t, app), Code: codes[0]}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Each Code Works Once
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login/mfa", user.LoginMFARequest{MFAToken: loginChallenge(t, app), Code: codes[0]}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+
+	// Regenerating Invalidates The Old Set
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/mfa/recovery-codes", user.MFACodeRequest{Code: codes[1]}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login/mfa", user.LoginMFARequest{MFAToken: loginChallenge(t, app), Code: codes[2]}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+}
+
+func TestDisableTOTP(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+	secret, _ := enrollTOTP(t, app, token)
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/mfa/totp/disable", user.MFACodeRequest{Code: "000000"}, token)
+	testutil.ExpectStatus(t, res, body, 400)
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/mfa/totp/disable", user.MFACodeRequest{Code: totpCode(t, secret, 1)}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Straight Back To Tokens At Login
+	testutil.Login(t, app, "tester", "123")
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/mfa/totp/disable", user.MFACodeRequest{Code: totpCode(t, secret, 1)}, token)
+	testutil.ExpectStatus(t, res, body, 404)
+}
@@ -2,12 +2,13 @@ package user
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 
 	"app/database"
+	"app/logging"
+	"app/tracing"
 )
 
 /*
@@ -15,33 +16,31 @@ Verifies That Account Is Enabled - JWT Tokens Are Valid Until Expiration
 This Additional Middleware Will Add A Way To Disable An Account Immediately
 */
 func VerifyAccountEnabled(c *fiber.Ctx) error {
+	ctx, span := tracing.Start(c.UserContext(), "user.VerifyAccountEnabled")
+
 	// Get UserID From Locals
 	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
 
 	if err != nil {
-		if DEBUG {
-			log.Printf("Verify Account Enabled Error: %s\n", err.Error())
-		}
+		logging.For(c).Error("Verify Account Enabled Error", "error", err)
+		tracing.End(span, err)
 		return c.SendStatus(500)
 	}
 
 	user := new(User)
 	user.ID = uint(user_id)
 
-	err = database.DB.First(&user).Error
+	err = database.DB.WithContext(ctx).First(&user).Error
+	span.End()
 
 	if err != nil {
-		if DEBUG {
-			log.Printf("Invalid JWT Token For User: %d\n", user_id)
-		}
+		logging.For(c).Debug("Invalid JWT Token For User", "user_id", user_id)
 		return c.SendStatus(401)
 	}
 
 	// Check If Account Is Enabled
 	if !*user.AccountEnabled {
-		if DEBUG {
-			log.Printf("Blocked Disabled Account: %s\n", user.Username)
-		}
+		logging.For(c).Info("Blocked Disabled Account", "username", user.Username)
 		return c.Status(403).JSON(fiber.Map{"error": "User Account Disabled"})
 	}
 	return c.Next()
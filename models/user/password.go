@@ -0,0 +1,185 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"app/api/auth"
+	"app/audit"
+	"app/config"
+	"app/database"
+	"app/logging"
+	"app/mail"
+	"app/util"
+)
+
+// Single Use - Only A SHA-256 Hash Of The Emailed Token Is Stored
+type PasswordResetToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"type:VARCHAR(64);not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+/*
+Emails A Reset Link If The Address Belongs To An Enabled Account
+Always Answers 202 So It Can't Be Used To Find Registered Emails
+*/
+func ForgotPassword(c *fiber.Ctx) error {
+	r := new(ForgotPasswordRequest)
+	err := c.BodyParser(r)
+
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+
+	r.Email = strings.TrimSpace(r.Email)
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	accepted := fiber.Map{"message": "If That Email Is Registered A Reset Link Is On Its Way"}
+
+	var user User
+	err = database.For(c).Where("email = ?", r.Email).First(&user).Error
+	if err != nil || !*user.AccountEnabled {
+		logging.For(c).Debug("Forgot Password: No Enabled Account", "email", r.Email)
+		return c.Status(202).JSON(accepted)
+	}
+
+	raw, err := issuePasswordResetToken(c.UserContext(), user.ID)
+	if err != nil {
+		logging.For(c).Error("Forgot Password Error", "error", err)
+		return c.SendStatus(500)
+	}
+
+	// Sent From The Outbox So The Request Never Waits On SMTP
+	// Still 202 On Failure - Anything Else Would Reveal The Account Exists
+	err = mail.Queue(c.UserContext(), user.Email, "password_reset", c.AcceptsLanguages(mail.Locales()...), passwordResetData(raw))
+	if err != nil {
+		logging.For(c).Error("Forgot Password: Failed To Queue Email", "error", err)
+	}
+
+	return c.Status(202).JSON(accepted)
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=1,max=32"`
+}
+
+// Consumes A Reset Token, Sets The New Password And Signs Out Every Session
+func ResetPassword(c *fiber.Ctx) error {
+	r := new(ResetPasswordRequest)
+	err := c.BodyParser(r)
+
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+
+	r.Password = strings.TrimSpace(r.Password)
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	var user User
+	err = database.For(c).Transaction(func(tx *gorm.DB) error {
+		var token PasswordResetToken
+		err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", auth.HashToken(r.Token), time.Now()).First(&token).Error
+		if err != nil {
+			return err
+		}
+
+		// Only One Concurrent Reset Can Win
+		res := tx.Model(&PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		err = tx.First(&user, token.UserID).Error
+		if err != nil {
+			return err
+		}
+		user.Password, err = HashPassword(c.UserContext(), user.Username, r.Password)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&user).Update("password", user.Password).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Or Expired Reset Token"})
+	}
+	if err != nil {
+		logging.For(c).Error("Reset Password Error", "error", err)
+		return c.SendStatus(500)
+	}
+
+	// Whoever Had The Old Password May Still Hold Tokens
+	err = auth.RevokeAllForUser(c.UserContext(), user.ID)
+	if err == nil {
+		err = auth.RevokePersonalAccessTokens(c.UserContext(), user.ID)
+	}
+	if err != nil {
+		logging.For(c).Error("Reset Password Error: Failed To Revoke Sessions", "error", err)
+		return c.SendStatus(500)
+	}
+	// A New Password Is A Fresh Start
+	clearLoginFailures(c.UserContext(), user.ID)
+	// Only The Account's Owner Should Have Had The Link
+	audit.Record(c, audit.Entry{Type: audit.PasswordReset, ActorID: user.ID, TargetType: audit.TargetUser, TargetID: user.ID})
+	return c.SendStatus(200)
+}
+
+// Replaces Any Outstanding Reset Tokens - Only The Latest Email Works
+func issuePasswordResetToken(ctx context.Context, userID uint) (string, error) {
+	raw, err := auth.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&PasswordResetToken{
+			UserID:    userID,
+			TokenHash: auth.HashToken(raw),
+			ExpiresAt: time.Now().Add(time.Duration(config.Get().Auth.PasswordResetExpires) * time.Second),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// Fills templates/*/password_reset.*
+func passwordResetData(raw string) fiber.Map {
+	cfg := config.Get()
+	return fiber.Map{
+		"Link":    strings.TrimSuffix(cfg.App.FrontendURL, "/") + "/reset-password?token=" + url.QueryEscape(raw),
+		"Minutes": cfg.Auth.PasswordResetExpires / 60,
+	}
+}
@@ -0,0 +1,74 @@
+package user_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"app/api/auth"
+	"app/database"
+	"app/models/user"
+	"app/testutil"
+)
+
+func TestPasswordReset(t *testing.T) {
+	app := testutil.Setup(t)
+	u := testutil.CreateUser(t, "tester", "123", "default")
+	oldToken := testutil.Login(t, app, "tester", "123")
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/password/forgot", user.ForgotPasswordRequest{Email: u.Email}, "")
+	testutil.ExpectStatus(t, res, body, 202)
+	token := testutil.LinkParam(t, testutil.LastMail(t, u.Email), "token")
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/password/reset", user.ResetPasswordRequest{Token: token, Password: "456"}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Only The New Password Works
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+	testutil.Login(t, app, "tester", "456")
+
+	// Existing Sessions Are Gone
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, oldToken)
+	testutil.ExpectStatus(t, res, body, 401)
+
+	// Tokens Are Single Use
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/password/reset", user.ResetPasswordRequest{Token: token, Password: "789"}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+}
+
+// Unknown Addresses Get The Same Answer And No Email
+func TestForgotPasswordNoEnumeration(t *testing.T) {
+	app := testutil.Setup(t)
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/password/forgot", user.ForgotPasswordRequest{Email: "nobody@tester.com"}, "")
+	testutil.ExpectStatus(t, res, body, 202)
+
+	if sent := testutil.DeliverMail(t).Messages(); len(sent) != 0 {
+		t.Fatalf("\nEmail Sent To Unknown Address: %s\n", sent[0].To)
+	}
+}
+
+func TestPasswordResetTokenRejected(t *testing.T) {
+	app := testutil.Setup(t)
+	u := testutil.CreateUser(t, "tester", "123", "default")
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/password/reset", user.ResetPasswordRequest{Token: "made-up", Password: "456"}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+
+	// Asking Again Invalidates The Earlier Link
+	testutil.Request(t, app, http.MethodPost, "/user/password/forgot", user.ForgotPasswordRequest{Email: u.Email}, "")
+	first := testutil.LinkParam(t, testutil.LastMail(t, u.Email), "token")
+	testutil.Request(t, app, http.MethodPost, "/user/password/forgot", user.ForgotPasswordRequest{Email: u.Email}, "")
+	second := testutil.LinkParam(t, testutil.LastMail(t, u.Email), "token")
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/password/reset", user.ResetPasswordRequest{Token: first, Password: "456"}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+
+	// Expired Links Don't Work
+	database.DB.Model(&user.PasswordResetToken{}).
+		Where("token_hash = ?", auth.HashToken(second)).
+		Update("expires_at", time.Now().Add(-time.Minute))
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/password/reset", user.ResetPasswordRequest{Token: second, Password: "456"}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+}
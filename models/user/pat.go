@@ -0,0 +1,121 @@
+package user
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/api/auth"
+	"app/audit"
+	"app/config"
+	"app/database"
+	"app/logging"
+	"app/util"
+)
+
+/*
+	Personal Access Tokens
+*/
+
+// ExpiresIn Is Seconds - auth.personal_access_token_expires When Left Out
+type CreatePersonalAccessTokenRequest struct {
+	Name      string   `json:"name" validate:"required,min=1,max=64"`
+	Scopes    []string `json:"scopes" validate:"omitempty,dive,required"`
+	ExpiresIn int64    `json:"expires_in" validate:"omitempty,min=1"`
+}
+
+/*
+The Raw Token Is Only In This Response - Scopes Must Be Permissions
+The User Holds Now, And Stop Working If Their Roles Lose Them Later
+*/
+func CreatePersonalAccessToken(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+
+	r := new(CreatePersonalAccessTokenRequest)
+	err = c.BodyParser(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+	r.Name = strings.TrimSpace(r.Name)
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	cfg := config.Get().Auth
+	if r.ExpiresIn == 0 {
+		r.ExpiresIn = cfg.PersonalAccessTokenExpires
+	}
+	if r.ExpiresIn > cfg.PersonalAccessTokenMaxExpires {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("Tokens Can't Last Longer Than %d Seconds", cfg.PersonalAccessTokenMaxExpires)})
+	}
+
+	granted, err := UserPermissions(c.UserContext(), uint(user_id))
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	for _, scope := range r.Scopes {
+		if !contains(granted, scope) {
+			return c.Status(403).JSON(fiber.Map{"error": "Missing Permission: " + scope})
+		}
+	}
+
+	raw, token, err := auth.IssuePersonalAccessToken(c.UserContext(), uint(user_id), r.Name, r.Scopes, time.Now().Add(time.Duration(r.ExpiresIn)*time.Second))
+	if err != nil {
+		logging.For(c).Error("Create Personal Access Token Error", "error", err)
+		return c.SendStatus(500)
+	}
+	audit.Record(c, audit.Entry{
+		Type:       audit.TokenCreated,
+		TargetType: audit.TargetToken,
+		TargetID:   token.ID,
+		Metadata:   fiber.Map{"name": token.Name, "prefix": token.Prefix, "scopes": token.Scopes, "expires_at": token.ExpiresAt},
+	})
+	return c.Status(201).JSON(fiber.Map{"token": raw, "personal_access_token": token})
+}
+
+// Live Tokens Only - Revoked And Expired Ones Are Left Out
+func GetPersonalAccessTokens(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+
+	var tokens []auth.PersonalAccessToken
+	err = database.For(c).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user_id, time.Now()).Order("id").Find(&tokens).Error
+	if err != nil {
+		logging.For(c).Error("Get Personal Access Tokens Error", "error", err)
+		return c.SendStatus(500)
+	}
+	return c.Status(200).JSON(fiber.Map{"personal_access_tokens": tokens})
+}
+
+func RevokePersonalAccessToken(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Token ID"})
+	}
+
+	revoked, err := auth.RevokePersonalAccessToken(c.UserContext(), uint(id), uint(user_id))
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	if !revoked {
+		return c.Status(404).JSON(fiber.Map{"error": "Token Doesn't Exist"})
+	}
+	audit.Record(c, audit.Entry{Type: audit.TokenRevoked, TargetType: audit.TargetToken, TargetID: uint(id)})
+	return c.SendStatus(200)
+}
@@ -0,0 +1,131 @@
+package user_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"app/api/auth"
+	"app/models/user"
+	"app/testutil"
+)
+
+type patResponse struct {
+	Token               string                   `json:"token"`
+	PersonalAccessToken auth.PersonalAccessToken `json:"personal_access_token"`
+}
+
+func TestPersonalAccessTokens(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "admin", "123", "admin")
+	login := testutil.Login(t, app, "admin", "123")
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/tokens", user.CreatePersonalAccessTokenRequest{Name: "ci", Scopes: []string{user.PermUsersRead}}, login)
+	testutil.ExpectStatus(t, res, body, 201)
+	created := new(patResponse)
+	testutil.Decode(t, body, created)
+
+	pat := created.PersonalAccessToken
+	if !strings.HasPrefix(created.Token, auth.PersonalAccessTokenPrefix+pat.Prefix+"_") {
+		t.Fatalf("\nToken %q Doesn't Carry Its Prefix %q\n", created.Token, pat.Prefix)
+	}
+	if strings.Contains(string(body), auth.HashToken(created.Token)) {
+		t.Fatalf("\nToken Hash Leaked: %s\n", body)
+	}
+	bearer := "Bearer " + created.Token
+
+	// Works Wherever A JWT Does
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, bearer)
+	testutil.ExpectStatus(t, res, body, 200)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/getall", nil, bearer)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// But Only Within Its Scopes
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/get-user-roles", nil, bearer)
+	testutil.ExpectStatus(t, res, body, 403)
+
+	// And Can't Mint, List Or Revoke Tokens Or Manage The Account
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/tokens", user.CreatePersonalAccessTokenRequest{Name: "more"}, bearer)
+	testutil.ExpectStatus(t, res, body, 401)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/tokens", nil, bearer)
+	testutil.ExpectStatus(t, res, body, 401)
+	res, body = testutil.Request(t, app, http.MethodDelete, fmt.Sprintf("/user/tokens/%d", pat.ID), nil, bearer)
+	testutil.ExpectStatus(t, res, body, 401)
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/update-password", nil, bearer)
+	testutil.ExpectStatus(t, res, body, 401)
+
+	// Listed Without The Secret
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/tokens", nil, login)
+	testutil.ExpectStatus(t, res, body, 200)
+	var list struct {
+		Tokens []auth.PersonalAccessToken `json:"personal_access_tokens"`
+	}
+	testutil.Decode(t, body, &list)
+	if len(list.Tokens) != 1 || list.Tokens[0].Name != "ci" || list.Tokens[0].LastUsedAt == nil {
+		t.Fatalf("\nUnexpected Tokens: %+v\n", list.Tokens)
+	}
+
+	// Revoked Tokens Stop Working
+	path := fmt.Sprintf("/user/tokens/%d", pat.ID)
+	res, body = testutil.Request(t, app, http.MethodDelete, path, nil, login)
+	testutil.ExpectStatus(t, res, body, 200)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, bearer)
+	testutil.ExpectStatus(t, res, body, 401)
+	res, body = testutil.Request(t, app, http.MethodDelete, path, nil, login)
+	testutil.ExpectStatus(t, res, body, 404)
+
+	// Tampered Or Made Up Tokens Are Refused
+	for _, bad := range []string{created.Token + "x", auth.PersonalAccessTokenPrefix + "nothing", auth.PersonalAccessTokenPrefix + "00000000_secret"} {
+		res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer "+bad)
+		testutil.ExpectStatus(t, res, body, 401)
+	}
+}
+
+func TestPersonalAccessTokenLimits(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	login := testutil.Login(t, app, "tester", "123")
+
+	// Only Permissions The User Holds
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/tokens", user.CreatePersonalAccessTokenRequest{Name: "ci", Scopes: []string{user.PermUsersRead}}, login)
+	testutil.ExpectStatus(t, res, body, 403)
+
+	// No Longer Than The Configured Maximum
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/tokens", user.CreatePersonalAccessTokenRequest{Name: "ci", ExpiresIn: 1 << 40}, login)
+	testutil.ExpectStatus(t, res, body, 400)
+
+	// Unscoped Tokens Still Authenticate But Hold No Permissions
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/tokens", user.CreatePersonalAccessTokenRequest{Name: "ci"}, login)
+	testutil.ExpectStatus(t, res, body, 201)
+	created := new(patResponse)
+	testutil.Decode(t, body, created)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer "+created.Token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+}
+
+// Logging Out Everywhere Leaves Scripts Running - A Password Reset Doesn't
+func TestPersonalAccessTokenRevokedOnReset(t *testing.T) {
+	app := testutil.Setup(t)
+	u := testutil.CreateUser(t, "tester", "123", "default")
+	login := testutil.Login(t, app, "tester", "123")
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/tokens", user.CreatePersonalAccessTokenRequest{Name: "ci"}, login)
+	testutil.ExpectStatus(t, res, body, 201)
+	created := new(patResponse)
+	testutil.Decode(t, body, created)
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/logout-all", nil, login)
+	testutil.ExpectStatus(t, res, body, 200)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer "+created.Token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/password/forgot", user.ForgotPasswordRequest{Email: u.Email}, "")
+	testutil.ExpectStatus(t, res, body, 202)
+	reset := testutil.LinkParam(t, testutil.LastMail(t, u.Email), "token")
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/password/reset", user.ResetPasswordRequest{Token: reset, Password: "456"}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer "+created.Token)
+	testutil.ExpectStatus(t, res, body, 401)
+}
@@ -0,0 +1,165 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/config"
+	"app/database"
+	"app/logging"
+	"app/tracing"
+)
+
+// Something A Role Allows - Named resource:action
+type Permission struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt   time.Time `json:"created_at"`
+	Name        string    `json:"name" gorm:"type:VARCHAR(64);not null;uniqueIndex"`
+	Description string    `json:"description" gorm:"type:VARCHAR(100)"`
+}
+
+// Permissions The API Checks - Seeded By database/seed
+const (
+	PermUsersRead  = "users:read"
+	PermUsersWrite = "users:write"
+	PermRolesRead  = "roles:read"
+	PermRolesWrite = "roles:write"
+	PermAuditRead  = "audit:read"
+)
+
+/*
+Allows The Request Only If The User's Roles Hold Every Permission
+Tokens Limited To Scopes Must Also Carry Them - Use ValidateJWT Or
+auth.Authenticate First. Personal Access Tokens Are Always Scoped
+*/
+func RequirePermission(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		missing, err := missingPermission(c, permissions...)
+		if err != nil {
+			logging.For(c).Error("Require Permission Error", "error", err)
+			return c.SendStatus(500)
+		}
+		if missing != "" {
+			logging.For(c).Info("Permission Denied", "user_id", c.Locals("user_id"), "permission", missing)
+			return c.Status(403).JSON(fiber.Map{"error": "Missing Permission: " + missing})
+		}
+		return c.Next()
+	}
+}
+
+// First Of permissions The Request Can't Use - Empty If It Can Use Them All
+func missingPermission(c *fiber.Ctx, permissions ...string) (string, error) {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, span := tracing.Start(c.UserContext(), "user.RequirePermission")
+	granted, err := UserPermissions(ctx, uint(user_id))
+	tracing.End(span, err)
+	if err != nil {
+		return "", err
+	}
+	scopes, _ := c.Locals("scopes").([]string)
+
+	for _, permission := range permissions {
+		if !contains(granted, permission) || (scopes != nil && !contains(scopes, permission)) {
+			return permission, nil
+		}
+	}
+	return "", nil
+}
+
+/*
+	Lookup
+*/
+
+// Cached For auth.permission_cache_ttl So Role Changes Apply Within That Long
+type permissionCache struct {
+	mu    sync.Mutex
+	users map[uint]permissionEntry
+}
+
+type permissionEntry struct {
+	permissions []string
+	until       time.Time
+}
+
+// Keeps The Cache From Growing Without Bound
+const maxCacheEntries = 10000
+
+var permissions = &permissionCache{users: map[uint]permissionEntry{}}
+
+// Drops Every Cached Lookup - Call After Changing Roles Or Their Permissions
+func ClearPermissionCache() {
+	permissions.mu.Lock()
+	defer permissions.mu.Unlock()
+	permissions.users = map[uint]permissionEntry{}
+}
+
+// Names Of Every Permission The User Holds Through Their Roles
+func UserPermissions(ctx context.Context, userID uint) ([]string, error) {
+	now := time.Now()
+
+	permissions.mu.Lock()
+	entry, ok := permissions.users[userID]
+	permissions.mu.Unlock()
+	if ok && now.Before(entry.until) {
+		return entry.permissions, nil
+	}
+
+	// The Primary Role Plus Any Active Grants
+	db := database.DB.WithContext(ctx)
+	var alive int64
+	err := db.Model(&User{}).Where("id = ?", userID).Count(&alive).Error
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	if alive != 0 {
+		roles := db.Model(&User{}).Select("role_id").Where("id = ?", userID)
+		granted := activeGrants(db.Model(&UserRoleGrant{}), now).Select("user_role_id").Where("user_id = ?", userID)
+		err = db.Table("permissions").
+			Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+			Where("role_permissions.user_role_id IN (?) OR role_permissions.user_role_id IN (?)", roles, granted).
+			Distinct().
+			Pluck("permissions.name", &names).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Don't Outlive The Next Grant To Expire
+	until := now.Add(time.Duration(config.Get().Auth.PermissionCacheTTL) * time.Second)
+	var next []UserRoleGrant
+	err = db.Where("user_id = ? AND expires_at > ?", userID, now).Order("expires_at").Limit(1).Find(&next).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(next) != 0 && next[0].ExpiresAt.Before(until) {
+		until = *next[0].ExpiresAt
+	}
+
+	permissions.mu.Lock()
+	if len(permissions.users) >= maxCacheEntries {
+		permissions.users = map[uint]permissionEntry{}
+	}
+	permissions.users[userID] = permissionEntry{permissions: names, until: until}
+	permissions.mu.Unlock()
+	return names, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
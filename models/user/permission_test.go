@@ -0,0 +1,109 @@
+package user_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"app/api/auth"
+	"app/config"
+	"app/database"
+	"app/models/user"
+	"app/testutil"
+)
+
+func TestSeededPermissions(t *testing.T) {
+	testutil.Setup(t)
+	admin := testutil.CreateUser(t, "admin", "123", "admin")
+	tester := testutil.CreateUser(t, "tester", "123", "default")
+
+	granted, err := user.UserPermissions(context.Background(), admin.ID)
+	if err != nil {
+		t.Fatalf("\nFailed To Read Permissions: %s\n", err.Error())
+	}
+	for _, p := range []string{user.PermUsersRead, user.PermUsersWrite, user.PermRolesRead, user.PermRolesWrite} {
+		found := false
+		for _, g := range granted {
+			found = found || g == p
+		}
+		if !found {
+			t.Fatalf("\nAdmin Is Missing %s: %v\n", p, granted)
+		}
+	}
+
+	granted, err = user.UserPermissions(context.Background(), tester.ID)
+	if err != nil {
+		t.Fatalf("\nFailed To Read Permissions: %s\n", err.Error())
+	}
+	if len(granted) != 0 {
+		t.Fatalf("\nDefault Role Shouldn't Hold Admin Permissions: %v\n", granted)
+	}
+}
+
+// Granting A Permission To A Role Opens Just That Route
+func TestRequirePermission(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+
+	res, body := testutil.Request(t, app, http.MethodGet, "/user/getall", nil, token)
+	testutil.ExpectStatus(t, res, body, 403)
+	if !strings.Contains(string(body), user.PermUsersRead) {
+		t.Fatalf("\nDenial Should Name The Permission: %s\n", body)
+	}
+
+	var permission user.Permission
+	err := database.DB.Where("name = ?", user.PermUsersRead).First(&permission).Error
+	if err != nil {
+		t.Fatalf("\nPermission Wasn't Seeded: %s\n", err.Error())
+	}
+	role := testutil.Role(t, "default")
+	err = database.DB.Model(&role).Association("Permissions").Append(&permission)
+	if err != nil {
+		t.Fatalf("\nFailed To Grant Permission: %s\n", err.Error())
+	}
+	user.ClearPermissionCache()
+
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/getall", nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Still Missing users:write
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", nil, token)
+	testutil.ExpectStatus(t, res, body, 403)
+}
+
+// A Token Limited To Scopes Can't Use Permissions Outside Them
+func TestRequirePermissionScopes(t *testing.T) {
+	app := testutil.Setup(t)
+	admin := testutil.CreateUser(t, "admin", "123", "admin")
+	cfg := config.Get().Auth
+
+	sign := func(scopes []string) string {
+		claims, err := auth.NewClaims(admin.ID, cfg.Audience, time.Minute)
+		if err != nil {
+			t.Fatalf("\nFailed To Build Claims: %s\n", err.Error())
+		}
+		claims.Scopes = scopes
+		keys, err := auth.Keys()
+		if err != nil {
+			t.Fatalf("\nNo Keys: %s\n", err.Error())
+		}
+		token, err := keys.Sign(claims)
+		if err != nil {
+			t.Fatalf("\nFailed To Sign: %s\n", err.Error())
+		}
+		return "Bearer " + token
+	}
+
+	token := sign([]string{user.PermRolesRead})
+	res, body := testutil.Request(t, app, http.MethodGet, "/user/get-user-roles", nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/getall", nil, token)
+	testutil.ExpectStatus(t, res, body, 403)
+
+	// Unscoped Tokens Get Everything The Role Has
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/getall", nil, sign(nil))
+	testutil.ExpectStatus(t, res, body, 200)
+}
@@ -0,0 +1,415 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"app/audit"
+	"app/database"
+	"app/logging"
+	"app/util"
+)
+
+// Every New Account Gets This Role - See CreateUser
+const DefaultRoleID = 1
+
+// A Role With How Many Accounts Hold It
+type RoleSummary struct {
+	ID          uint         `json:"id"`
+	Role        string       `json:"role"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+	UserCount   int64        `json:"user_count"`
+}
+
+var (
+	errRoleNotFound    = errors.New("Role Doesn't Exist")
+	errLastRoleManager = errors.New("At Least One Role Must Keep " + PermRolesWrite)
+)
+
+/*
+	Roles
+*/
+
+func GetRoles(c *fiber.Ctx) error {
+	var roles []UserRole
+	err := database.For(c).Preload("Permissions").Order("id").Find(&roles).Error
+	if err != nil {
+		logging.For(c).Error("Get Roles Error", "error", err)
+		return c.SendStatus(500)
+	}
+
+	// Primary Roles And Active Grants - Each User Counted Once Per Role
+	var counts []struct {
+		RoleID uint
+		Count  int64
+	}
+	err = database.For(c).Raw(`SELECT role_id, COUNT(DISTINCT user_id) AS count FROM (
+		SELECT id AS user_id, role_id FROM users WHERE deleted_at IS NULL
+		UNION SELECT user_id, user_role_id AS role_id FROM user_role_grants WHERE expires_at IS NULL OR expires_at > ?
+	) holders GROUP BY role_id`, time.Now()).Scan(&counts).Error
+	if err != nil {
+		logging.For(c).Error("Get Roles Error: Failed Counting Users", "error", err)
+		return c.SendStatus(500)
+	}
+	held := map[uint]int64{}
+	for _, count := range counts {
+		held[count.RoleID] = count.Count
+	}
+
+	summaries := make([]RoleSummary, len(roles))
+	for i, role := range roles {
+		summaries[i] = RoleSummary{
+			ID:          role.ID,
+			Role:        role.Role,
+			Description: role.Description,
+			Permissions: role.Permissions,
+			UserCount:   held[role.ID],
+		}
+	}
+	return c.Status(200).JSON(fiber.Map{"roles": summaries})
+}
+
+func GetPermissions(c *fiber.Ctx) error {
+	var permissions []Permission
+	err := database.For(c).Order("name").Find(&permissions).Error
+	if err != nil {
+		logging.For(c).Error("Get Permissions Error", "error", err)
+		return c.SendStatus(500)
+	}
+	return c.Status(200).JSON(fiber.Map{"permissions": permissions})
+}
+
+type CreateRoleRequest struct {
+	Role        string   `json:"role" validate:"required,min=1,max=32"`
+	Description string   `json:"description" validate:"omitempty,max=100"`
+	Permissions []string `json:"permissions" validate:"omitempty"`
+}
+
+func CreateRole(c *fiber.Ctx) error {
+	r := new(CreateRoleRequest)
+	err := c.BodyParser(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+	r.Role = strings.TrimSpace(strings.ToLower(r.Role))
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	taken, err := roleNameTaken(c.UserContext(), r.Role, 0)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	if taken {
+		return c.Status(409).JSON(fiber.Map{"error": "Role Already Exists"})
+	}
+
+	granted, unknown, err := permissionsNamed(c.UserContext(), r.Permissions)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	if unknown != "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Unknown Permission: " + unknown})
+	}
+
+	role := UserRole{Role: r.Role, Description: strings.TrimSpace(r.Description), Permissions: granted}
+	err = database.For(c).Create(&role).Error
+	if err != nil {
+		logging.For(c).Error("Create Role Error", "error", err)
+		return c.SendStatus(500)
+	}
+	audit.Record(c, audit.Entry{Type: audit.RoleCreated, TargetType: audit.TargetRole, TargetID: role.ID, After: auditRole(&role)})
+	return c.Status(201).JSON(fiber.Map{"role": role})
+}
+
+// Omitted Fields Are Left As They Are
+type UpdateRoleRequest struct {
+	Role        *string `json:"role" validate:"omitempty,min=1,max=32"`
+	Description *string `json:"description" validate:"omitempty,max=100"`
+}
+
+func UpdateRole(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Role ID"})
+	}
+
+	r := new(UpdateRoleRequest)
+	err = c.BodyParser(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+	if r.Role != nil {
+		*r.Role = strings.TrimSpace(strings.ToLower(*r.Role))
+	}
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	var role UserRole
+	err = database.For(c).Preload("Permissions").First(&role, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": errRoleNotFound.Error()})
+	}
+	if err != nil {
+		return c.SendStatus(500)
+	}
+
+	before := auditRole(&role)
+	updates := map[string]interface{}{}
+	if r.Role != nil && *r.Role != role.Role {
+		taken, err := roleNameTaken(c.UserContext(), *r.Role, role.ID)
+		if err != nil {
+			return c.SendStatus(500)
+		}
+		if taken {
+			return c.Status(409).JSON(fiber.Map{"error": "Role Already Exists"})
+		}
+		updates["role"] = *r.Role
+	}
+	if r.Description != nil {
+		updates["description"] = strings.TrimSpace(*r.Description)
+	}
+	if len(updates) != 0 {
+		err = database.For(c).Model(&role).Updates(updates).Error
+		if err != nil {
+			logging.For(c).Error("Update Role Error", "error", err)
+			return c.SendStatus(500)
+		}
+	}
+
+	database.For(c).Preload("Permissions").First(&role, role.ID)
+	if len(updates) != 0 {
+		audit.Record(c, audit.Entry{Type: audit.RoleUpdated, TargetType: audit.TargetRole, TargetID: role.ID, Before: before, After: auditRole(&role)})
+	}
+	return c.Status(200).JSON(fiber.Map{"role": role})
+}
+
+/*
+Roles Still Held By Anyone - Including Soft Deleted Accounts And Unexpired Grants - Are Kept
+As Are The Default Role And The Last Role That Can Manage Roles
+*/
+func DeleteRole(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Role ID"})
+	}
+	if id == DefaultRoleID {
+		return c.Status(409).JSON(fiber.Map{"error": "The Default Role Can't Be Deleted"})
+	}
+
+	var holders int64
+	var before fiber.Map
+	err = database.For(c).Transaction(func(tx *gorm.DB) error {
+		var role UserRole
+		err := tx.Preload("Permissions").First(&role, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errRoleNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.Unscoped().Model(&User{}).Where("role_id = ?", role.ID).Count(&holders).Error
+		if err != nil || holders != 0 {
+			return err
+		}
+		// Expired Grants Don't Hold It Up
+		err = tx.Where("user_role_id = ? AND expires_at IS NOT NULL AND expires_at <= ?", role.ID, time.Now()).Delete(&UserRoleGrant{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&UserRoleGrant{}).Where("user_role_id = ?", role.ID).Count(&holders).Error
+		if err != nil || holders != 0 {
+			return err
+		}
+
+		err = keepRoleManager(tx, role.ID)
+		if err != nil {
+			return err
+		}
+
+		before = auditRole(&role)
+		err = tx.Model(&role).Association("Permissions").Clear()
+		if err != nil {
+			return err
+		}
+		// Hard Delete So The Name Can Be Used Again
+		return tx.Unscoped().Delete(&role).Error
+	})
+	switch {
+	case errors.Is(err, errRoleNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errLastRoleManager):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		logging.For(c).Error("Delete Role Error", "error", err)
+		return c.SendStatus(500)
+	case holders != 0:
+		return c.Status(409).JSON(fiber.Map{"error": "Role Is Still Assigned", "user_count": holders})
+	}
+
+	ClearPermissionCache()
+	audit.Record(c, audit.Entry{Type: audit.RoleDeleted, TargetType: audit.TargetRole, TargetID: uint(id), Before: before})
+	return c.SendStatus(200)
+}
+
+/*
+	Role Permissions
+*/
+
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions" validate:"required,min=1"`
+}
+
+// Grants Each Named Permission - Ones The Role Already Has Are Ignored
+func AttachRolePermissions(c *fiber.Ctx) error {
+	return changeRolePermissions(c, true)
+}
+
+// Revokes Each Named Permission - Ones The Role Doesn't Have Are Ignored
+func DetachRolePermissions(c *fiber.Ctx) error {
+	return changeRolePermissions(c, false)
+}
+
+func changeRolePermissions(c *fiber.Ctx, attach bool) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Role ID"})
+	}
+
+	r := new(RolePermissionsRequest)
+	err = c.BodyParser(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	named, unknown, err := permissionsNamed(c.UserContext(), r.Permissions)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	if unknown != "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Unknown Permission: " + unknown})
+	}
+
+	var role UserRole
+	var before fiber.Map
+	err = database.For(c).Transaction(func(tx *gorm.DB) error {
+		err := tx.Preload("Permissions").First(&role, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errRoleNotFound
+		}
+		if err != nil {
+			return err
+		}
+		before = auditRole(&role)
+
+		if attach {
+			return tx.Model(&role).Association("Permissions").Append(named)
+		}
+		if contains(r.Permissions, PermRolesWrite) {
+			err = keepRoleManager(tx, role.ID)
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&role).Association("Permissions").Delete(named)
+	})
+	switch {
+	case errors.Is(err, errRoleNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, errLastRoleManager):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		logging.For(c).Error("Change Role Permissions Error", "error", err)
+		return c.SendStatus(500)
+	}
+
+	ClearPermissionCache()
+	database.For(c).Preload("Permissions").First(&role, role.ID)
+	audit.Record(c, audit.Entry{Type: audit.RolePermissionsChanged, TargetType: audit.TargetRole, TargetID: role.ID, Before: before, After: auditRole(&role)})
+	return c.Status(200).JSON(fiber.Map{"role": role})
+}
+
+/*
+	Helpers
+*/
+
+// Name, Description And Sorted Permission Names - What Audit Events Compare
+func auditRole(role *UserRole) fiber.Map {
+	permissions := make([]string, len(role.Permissions))
+	for i, permission := range role.Permissions {
+		permissions[i] = permission.Name
+	}
+	sort.Strings(permissions)
+	return fiber.Map{"role": role.Role, "description": role.Description, "permissions": permissions}
+}
+
+// Soft Deleted Roles Still Hold Their Name In The Unique Index
+func roleNameTaken(ctx context.Context, name string, exceptID uint) (bool, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).Unscoped().Model(&UserRole{}).Where("role = ? AND id <> ?", name, exceptID).Count(&count).Error
+	return count != 0, err
+}
+
+// Looks Up Permissions By Name - unknown Is The First Name That Doesn't Exist
+func permissionsNamed(ctx context.Context, names []string) ([]Permission, string, error) {
+	if len(names) == 0 {
+		return nil, "", nil
+	}
+	var found []Permission
+	err := database.DB.WithContext(ctx).Where("name IN ?", names).Find(&found).Error
+	if err != nil {
+		return nil, "", err
+	}
+	for _, name := range names {
+		known := false
+		for _, p := range found {
+			known = known || p.Name == name
+		}
+		if !known {
+			return nil, name, nil
+		}
+	}
+	return found, "", nil
+}
+
+/*
+errLastRoleManager If roleID Is The Only Held Role With roles:write - Roles
+Nobody Holds, Directly Or Through An Active Grant, Can't Manage Anything
+*/
+func keepRoleManager(tx *gorm.DB, roleID uint) error {
+	held := tx.Model(&User{}).Select("role_id").Where("account_enabled = ?", true)
+	granted := activeGrants(tx.Model(&UserRoleGrant{}), time.Now()).
+		Joins("JOIN users ON users.id = user_role_grants.user_id AND users.deleted_at IS NULL AND users.account_enabled = ?", true).
+		Select("user_role_grants.user_role_id")
+
+	var managers []uint
+	err := tx.Table("role_permissions").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Joins("JOIN user_roles ON user_roles.id = role_permissions.user_role_id AND user_roles.deleted_at IS NULL").
+		Where("permissions.name = ?", PermRolesWrite).
+		Where("role_permissions.user_role_id IN (?) OR role_permissions.user_role_id IN (?)", held, granted).
+		Distinct().
+		Pluck("role_permissions.user_role_id", &managers).Error
+	if err != nil {
+		return err
+	}
+	if len(managers) == 1 && managers[0] == roleID {
+		return errLastRoleManager
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package user_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/models/user"
+	"app/testutil"
+)
+
+type roleResponse struct {
+	Role user.UserRole `json:"role"`
+}
+
+func TestRoleLifecycle(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "admin", "123", "admin")
+	tester := testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "admin", "123")
+
+	// Create
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/roles", user.CreateRoleRequest{Role: " Support ", Description: "Helpdesk", Permissions: []string{user.PermUsersRead}}, token)
+	testutil.ExpectStatus(t, res, body, 201)
+	created := new(roleResponse)
+	testutil.Decode(t, body, created)
+	if created.Role.Role != "support" || len(created.Role.Permissions) != 1 {
+		t.Fatalf("\nUnexpected Role: %+v\n", created.Role)
+	}
+	path := fmt.Sprintf("/user/roles/%d", created.Role.ID)
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/roles", user.CreateRoleRequest{Role: "support"}, token)
+	testutil.ExpectStatus(t, res, body, 409)
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/roles", user.CreateRoleRequest{Role: "other", Permissions: []string{"nothing:ever"}}, token)
+	testutil.ExpectStatus(t, res, body, 400)
+
+	// Rename And Describe
+	res, body = testutil.Request(t, app, http.MethodPut, path, fiber.Map{"role": "helpdesk", "description": "First Line"}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	updated := new(roleResponse)
+	testutil.Decode(t, body, updated)
+	if updated.Role.Role != "helpdesk" || updated.Role.Description != "First Line" {
+		t.Fatalf("\nRole Wasn't Updated: %+v\n", updated.Role)
+	}
+	res, body = testutil.Request(t, app, http.MethodPut, path, fiber.Map{"role": "admin"}, token)
+	testutil.ExpectStatus(t, res, body, 409)
+
+	// Attach And Detach
+	res, body = testutil.Request(t, app, http.MethodPost, path+"/permissions", user.RolePermissionsRequest{Permissions: []string{user.PermRolesRead, user.PermUsersRead}}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	testutil.Decode(t, body, updated)
+	if len(updated.Role.Permissions) != 2 {
+		t.Fatalf("\nExpected 2 Permissions: %+v\n", updated.Role.Permissions)
+	}
+	res, body = testutil.Request(t, app, http.MethodDelete, path+"/permissions", user.RolePermissionsRequest{Permissions: []string{user.PermUsersRead}}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	testutil.Decode(t, body, updated)
+	if len(updated.Role.Permissions) != 1 || updated.Role.Permissions[0].Name != user.PermRolesRead {
+		t.Fatalf("\nExpected Only %s: %+v\n", user.PermRolesRead, updated.Role.Permissions)
+	}
+
+	// Held Roles Can't Be Deleted
+	enabled := true
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", user.AdminUserUpdateRequest{UserID: tester.ID, RoleID: created.Role.ID, Email: tester.Email, Account_enabled: &enabled}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/roles", nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	var roles struct {
+		Roles []user.RoleSummary `json:"roles"`
+	}
+	testutil.Decode(t, body, &roles)
+	for _, role := range roles.Roles {
+		if role.ID == created.Role.ID && role.UserCount != 1 {
+			t.Fatalf("\nUnexpected User Count: %d Expected: 1\n", role.UserCount)
+		}
+	}
+
+	res, body = testutil.Request(t, app, http.MethodDelete, path, nil, token)
+	testutil.ExpectStatus(t, res, body, 409)
+
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", user.AdminUserUpdateRequest{UserID: tester.ID, RoleID: user.DefaultRoleID, Email: tester.Email, Account_enabled: &enabled}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	res, body = testutil.Request(t, app, http.MethodDelete, path, nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	res, body = testutil.Request(t, app, http.MethodDelete, path, nil, token)
+	testutil.ExpectStatus(t, res, body, 404)
+
+	// The Name Is Free Again
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/roles", user.CreateRoleRequest{Role: "helpdesk"}, token)
+	testutil.ExpectStatus(t, res, body, 201)
+}
+
+// Admins Can't Lock Everyone Out Of Role Management
+func TestKeepRoleManager(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "admin", "123", "admin")
+	token := testutil.Login(t, app, "admin", "123")
+	admin := testutil.Role(t, "admin")
+	path := fmt.Sprintf("/user/roles/%d", admin.ID)
+
+	res, body := testutil.Request(t, app, http.MethodDelete, path+"/permissions", user.RolePermissionsRequest{Permissions: []string{user.PermRolesWrite}}, token)
+	testutil.ExpectStatus(t, res, body, 409)
+
+	res, body = testutil.Request(t, app, http.MethodDelete, fmt.Sprintf("/user/roles/%d", user.DefaultRoleID), nil, token)
+	testutil.ExpectStatus(t, res, body, 409)
+
+	// A Role Nobody Holds Doesn't Count
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/roles", user.CreateRoleRequest{Role: "owner", Permissions: []string{user.PermRolesWrite}}, token)
+	testutil.ExpectStatus(t, res, body, 201)
+	res, body = testutil.Request(t, app, http.MethodDelete, path+"/permissions", user.RolePermissionsRequest{Permissions: []string{user.PermRolesWrite}}, token)
+	testutil.ExpectStatus(t, res, body, 409)
+
+	// Once Someone Else Can Manage Roles It's Fine
+	testutil.CreateUser(t, "owner", "123", "owner")
+	res, body = testutil.Request(t, app, http.MethodDelete, path+"/permissions", user.RolePermissionsRequest{Permissions: []string{user.PermRolesWrite}}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Applies Straight Away
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/roles", user.CreateRoleRequest{Role: "another"}, token)
+	testutil.ExpectStatus(t, res, body, 403)
+}
@@ -0,0 +1,211 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"app/audit"
+	"app/config"
+	"app/database"
+	"app/logging"
+	"app/mail"
+	"app/metrics"
+	"app/util"
+)
+
+/*
+Failed Logins For One Account ("user:<id>") Or One Address ("ip:<addr>")
+RetryAt Is The Progressive Delay, LockedUntil A Full Lockout
+*/
+type LoginThrottle struct {
+	Target        string     `json:"target" gorm:"type:VARCHAR(80);primaryKey"`
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt time.Time  `json:"last_failure_at" gorm:"not null;index"`
+	RetryAt       *time.Time `json:"retry_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
+
+func accountTarget(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+func addressTarget(ip string) string {
+	return "ip:" + ip
+}
+
+// The First Target Still Having To Wait - Nil When Every One Can Try Now
+func loginThrottle(ctx context.Context, targets ...string) (*LoginThrottle, error) {
+	if !config.Get().Lockout.Enabled {
+		return nil, nil
+	}
+
+	var throttles []LoginThrottle
+	err := database.DB.WithContext(ctx).Where("target IN ?", targets).Find(&throttles).Error
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i, t := range throttles {
+		if (t.LockedUntil != nil && now.Before(*t.LockedUntil)) || (t.RetryAt != nil && now.Before(*t.RetryAt)) {
+			return &throttles[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// 423 For A Locked Account, 429 Otherwise - Both With Retry-After
+func throttled(c *fiber.Ctx, t *LoginThrottle) error {
+	until := t.RetryAt
+	if t.LockedUntil != nil && time.Now().Before(*t.LockedUntil) {
+		until = t.LockedUntil
+	}
+	c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(int64(math.Ceil(time.Until(*until).Seconds())), 10))
+
+	if until == t.LockedUntil && strings.HasPrefix(t.Target, "user:") {
+		metrics.LoginsRejected.WithLabelValues("locked").Inc()
+		return c.Status(423).JSON(fiber.Map{"error": "Account Locked - Try Again Later", "locked_until": until})
+	}
+	metrics.LoginsRejected.WithLabelValues("throttled").Inc()
+	return c.Status(429).JSON(fiber.Map{"error": "Too Many Failed Logins - Try Again Later"})
+}
+
+/*
+Counts A Failure Against The Target - Returns When It Was Locked If This
+Failure Locked It. Locking Starts The Count Again For When It Unlocks
+*/
+func recordLoginFailure(ctx context.Context, target string, threshold int) (*time.Time, error) {
+	cfg := config.Get().Lockout
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	now := time.Now()
+	db := database.DB.WithContext(ctx)
+
+	// Counted In One Statement So Concurrent Failures All Count
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "target"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":        gorm.Expr("CASE WHEN login_throttles.last_failure_at < ? THEN 1 ELSE login_throttles.failures + 1 END", now.Add(-time.Duration(cfg.Window)*time.Second)),
+			"last_failure_at": now,
+		}),
+	}).Create(&LoginThrottle{Target: target, Failures: 1, LastFailureAt: now}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var throttle LoginThrottle
+	err = db.Where("target = ?", target).First(&throttle).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Forget Addresses And Accounts That Have Gone Quiet
+	db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-time.Duration(cfg.Window)*time.Second), now).Delete(&LoginThrottle{})
+
+	switch {
+	case throttle.Failures >= threshold:
+		until := now.Add(time.Duration(cfg.Duration) * time.Second)
+		err = db.Model(&throttle).Updates(map[string]interface{}{"failures": 0, "retry_at": nil, "locked_until": until}).Error
+		return &until, err
+	case throttle.Failures >= cfg.DelayAfter:
+		// delay, 2 * delay, 4 * delay ... Up To max_delay
+		wait := cfg.MaxDelay
+		if shift := throttle.Failures - cfg.DelayAfter; shift < 32 && cfg.Delay<<shift < cfg.MaxDelay {
+			wait = cfg.Delay << shift
+		}
+		err = db.Model(&throttle).Update("retry_at", now.Add(time.Duration(wait)*time.Second)).Error
+		return nil, err
+	}
+	return nil, nil
+}
+
+/*
+A Failed Password Or Second Factor - Counts Against The Account And The Address
+user Is Nil When Nobody Has The Username, reason Goes In The Audit Log
+*/
+func loginFailed(c *fiber.Ctx, username string, user *User, reason string) {
+	auditLoginFailed(c, username, user, reason)
+
+	cfg := config.Get().Lockout
+	_, err := recordLoginFailure(c.UserContext(), addressTarget(c.IP()), cfg.IPThreshold)
+	if err != nil {
+		logging.For(c).Error("Login Throttle Error", "error", err)
+	}
+	if user == nil {
+		return
+	}
+
+	locked, err := recordLoginFailure(c.UserContext(), accountTarget(user.ID), cfg.Threshold)
+	if err != nil {
+		logging.For(c).Error("Login Throttle Error", "error", err)
+	}
+	if locked == nil {
+		return
+	}
+	metrics.AccountLockouts.Inc()
+	audit.Record(c, audit.Entry{
+		Type:       audit.AccountLocked,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Metadata:   fiber.Map{"locked_until": locked},
+	})
+	logging.For(c).Warn("Locked Account", "username", user.Username, "locked_until", *locked)
+	err = mail.Queue(c.UserContext(), user.Email, "account_locked", c.AcceptsLanguages(mail.Locales()...), fiber.Map{
+		"Username": user.Username,
+		"Minutes":  cfg.Duration / 60,
+		"Link":     strings.TrimSuffix(config.Get().App.FrontendURL, "/") + "/forgot-password",
+	})
+	if err != nil {
+		logging.For(c).Error("Login Throttle: Failed To Queue Lockout Email", "error", err)
+	}
+}
+
+// Forgets The Account's Failures And Any Lockout
+func clearLoginFailures(ctx context.Context, userID uint) error {
+	return database.DB.WithContext(ctx).Where("target = ?", accountTarget(userID)).Delete(&LoginThrottle{}).Error
+}
+
+/*
+	Admin
+*/
+
+type AdminUnlockUserRequest struct {
+	UserID uint `json:"user_id" validate:"required,number"`
+}
+
+func AdminUnlockUser(c *fiber.Ctx) error {
+	r := new(AdminUnlockUserRequest)
+	err := c.BodyParser(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	var count int64
+	err = database.For(c).Model(&User{}).Where("id = ?", r.UserID).Count(&count).Error
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	if count == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "User Doesn't Exist"})
+	}
+
+	err = clearLoginFailures(c.UserContext(), r.UserID)
+	if err != nil {
+		logging.For(c).Error("Admin Unlock Error", "error", err)
+		return c.SendStatus(500)
+	}
+	audit.Record(c, audit.Entry{Type: audit.AdminUserUnlocked, TargetType: audit.TargetUser, TargetID: r.UserID})
+	return c.SendStatus(200)
+}
@@ -0,0 +1,113 @@
+package user_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/database"
+	"app/models/user"
+	"app/testutil"
+)
+
+func lockoutApp(t *testing.T) *fiber.App {
+	cfg := testutil.Config(t)
+	cfg.Lockout.DelayAfter = 2
+	cfg.Lockout.Delay = 60
+	cfg.Lockout.MaxDelay = 60
+	cfg.Lockout.Threshold = 4
+	cfg.Lockout.IPThreshold = 6
+	return testutil.SetupWith(t, cfg)
+}
+
+// Lets The Next Attempt Through Without Waiting Out The Delay
+func skipDelay(t *testing.T) {
+	t.Helper()
+	err := database.DB.Model(&user.LoginThrottle{}).Where("1 = 1").Update("retry_at", nil).Error
+	if err != nil {
+		t.Fatalf("\nFailed To Clear Delay: %s\n", err.Error())
+	}
+}
+
+func TestLoginDelay(t *testing.T) {
+	app := lockoutApp(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	wrong := user.LoginRequest{Username: "tester", Password: "wrong"}
+
+	for i := 0; i < 2; i++ {
+		res, body := testutil.Request(t, app, http.MethodPost, "/user/login", wrong, "")
+		testutil.ExpectStatus(t, res, body, 400)
+	}
+
+	// Even The Right Password Has To Wait
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 429)
+	if res.Header.Get("Retry-After") != "60" {
+		t.Fatalf("\nUnexpected Retry-After: %q Expected: 60\n", res.Header.Get("Retry-After"))
+	}
+
+	// Success Clears The Account's Failures
+	skipDelay(t)
+	testutil.Login(t, app, "tester", "123")
+	var count int64
+	database.DB.Model(&user.LoginThrottle{}).Where("target LIKE ?", "user:%").Count(&count)
+	if count != 0 {
+		t.Fatalf("\nAccount Failures Weren't Cleared\n")
+	}
+}
+
+func TestAccountLockout(t *testing.T) {
+	app := lockoutApp(t)
+	testutil.CreateUser(t, "admin", "123", "admin")
+	tester := testutil.CreateUser(t, "tester", "123", "default")
+	adminToken := testutil.Login(t, app, "admin", "123")
+	wrong := user.LoginRequest{Username: "tester", Password: "wrong"}
+
+	for i := 0; i < 3; i++ {
+		skipDelay(t)
+		res, body := testutil.Request(t, app, http.MethodPost, "/user/login", wrong, "")
+		testutil.ExpectStatus(t, res, body, 400)
+	}
+	skipDelay(t)
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/login", wrong, "")
+	testutil.ExpectStatus(t, res, body, 400)
+
+	// Locked - Right Password Or Not
+	skipDelay(t)
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 423)
+	if res.Header.Get("Retry-After") == "" {
+		t.Fatalf("\nLockout Is Missing Retry-After\n")
+	}
+
+	// The Owner Is Told
+	msg := testutil.LastMail(t, tester.Email)
+	if msg.Subject != "Your account has been locked" {
+		t.Fatalf("\nUnexpected Mail: %s\n", msg.Subject)
+	}
+
+	// Until An Admin Unlocks It
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/admin-unlock", user.AdminUnlockUserRequest{UserID: tester.ID}, adminToken)
+	testutil.ExpectStatus(t, res, body, 200)
+	skipDelay(t)
+	testutil.Login(t, app, "tester", "123")
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/admin-unlock", user.AdminUnlockUserRequest{UserID: 999}, adminToken)
+	testutil.ExpectStatus(t, res, body, 404)
+}
+
+// Guessing Across Many Usernames Still Trips The Address
+func TestAddressLockout(t *testing.T) {
+	app := lockoutApp(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+
+	for _, username := range []string{"a", "b", "c", "d", "e", "f"} {
+		skipDelay(t)
+		res, body := testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: username, Password: "wrong"}, "")
+		testutil.ExpectStatus(t, res, body, 400)
+	}
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 429)
+}
@@ -0,0 +1,142 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/api/auth"
+	"app/audit"
+	"app/database"
+	"app/logging"
+	"app/util"
+)
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// Rotates A Refresh Token - Replaying An Old One Revokes The Whole Family
+func RefreshToken(c *fiber.Ctx) error {
+	r := new(RefreshTokenRequest)
+	err := c.BodyParser(r)
+
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	user_id, refreshToken, err := auth.RotateRefreshToken(c.UserContext(), r.RefreshToken)
+	if err != nil {
+		logging.For(c).Debug("Refresh Token Error", "error", err)
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			return c.Status(401).JSON(fiber.Map{"error": "Refresh Token Reused - Please Login Again"})
+		}
+		return c.Status(401).JSON(fiber.Map{"error": "Invalid Refresh Token"})
+	}
+
+	// Deleted Or Disabled Accounts Can't Refresh
+	var user User
+	err = database.For(c).Preload("Role").First(&user, user_id).Error
+	if err != nil {
+		logging.For(c).Debug("Refresh Token Error: User Doesn't Exist", "user_id", user_id)
+		return c.Status(401).JSON(fiber.Map{"error": "Invalid Refresh Token"})
+	}
+	if !*user.AccountEnabled {
+		return c.Status(403).JSON(fiber.Map{"error": "User Account Disabled"})
+	}
+
+	token, err := issueJWT(c.UserContext(), &user)
+	if err != nil {
+		logging.For(c).Error("Refresh Token JWT Error", "error", err)
+		return c.SendStatus(500)
+	}
+	return c.Status(200).JSON(fiber.Map{"token": token, "refresh_token": refreshToken})
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"omitempty"`
+}
+
+// Revokes The Current Access Token And, If Given, Its Refresh Token
+func Logout(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		logging.For(c).Error("Logout Error: Failed Parsing Uint", "error", err)
+		return c.SendStatus(500)
+	}
+
+	// Body Is Optional
+	r := new(LogoutRequest)
+	if len(c.Body()) > 0 {
+		err = c.BodyParser(r)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+		}
+	}
+
+	jti := fmt.Sprintf("%s", c.Locals("jti"))
+	exp, _ := c.Locals("token_exp").(time.Time)
+	err = auth.RevokeToken(c.UserContext(), jti, uint(user_id), exp)
+	if err != nil {
+		logging.For(c).Error("Logout Error", "error", err)
+		return c.SendStatus(500)
+	}
+
+	if r.RefreshToken != "" {
+		err = auth.RevokeRefreshToken(c.UserContext(), r.RefreshToken, uint(user_id))
+		if err != nil {
+			logging.For(c).Debug("Logout Refresh Token Error", "error", err)
+		}
+	}
+	return c.SendStatus(200)
+}
+
+// Revokes Every Access And Refresh Token The User Holds - Personal Access Tokens Stay
+func LogoutAll(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		logging.For(c).Error("Logout All Error: Failed Parsing Uint", "error", err)
+		return c.SendStatus(500)
+	}
+
+	err = auth.RevokeAllForUser(c.UserContext(), uint(user_id))
+	if err != nil {
+		logging.For(c).Error("Logout All Error", "error", err)
+		return c.SendStatus(500)
+	}
+	audit.Record(c, audit.Entry{Type: audit.SessionsRevoked, TargetType: audit.TargetUser, TargetID: uint(user_id)})
+	return c.SendStatus(200)
+}
+
+// Access Token Plus A New Refresh Token Family
+func issueTokens(ctx context.Context, user *User) (string, string, error) {
+	token, err := issueJWT(ctx, user)
+	if err != nil {
+		return "", "", err
+	}
+	refreshToken, err := auth.IssueRefreshToken(ctx, user.ID)
+	if err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
+// Access Token Carrying The Primary Role And Every Active Grant
+func issueJWT(ctx context.Context, user *User) (string, error) {
+	roles, err := RoleNames(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	return auth.IssueJWT(ctx, user.ID, user.Role.Role, roles)
+}
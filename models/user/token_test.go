@@ -0,0 +1,161 @@
+package user_test
+
+import (
+	"net/http"
+	"testing"
+
+	"app/api/auth"
+	"app/models/user"
+	"app/testutil"
+)
+
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+	first := new(TokenResponse)
+	testutil.Decode(t, body, first)
+
+	if first.RefreshToken == "" {
+		t.Fatalf("\nLogin Didn't Return A Refresh Token\n")
+	}
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/token/refresh", user.RefreshTokenRequest{RefreshToken: first.RefreshToken}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+	second := new(TokenResponse)
+	testutil.Decode(t, body, second)
+
+	if second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("\nRefresh Token Wasn't Rotated\n")
+	}
+
+	// The New Access Token Works
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer "+second.Token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// So Does The New Refresh Token
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/token/refresh", user.RefreshTokenRequest{RefreshToken: second.RefreshToken}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+}
+
+// Replaying A Used Refresh Token Revokes Every Token In Its Family
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+	first := new(TokenResponse)
+	testutil.Decode(t, body, first)
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/token/refresh", user.RefreshTokenRequest{RefreshToken: first.RefreshToken}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+	second := new(TokenResponse)
+	testutil.Decode(t, body, second)
+
+	// Attacker Replays The First Token
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/token/refresh", user.RefreshTokenRequest{RefreshToken: first.RefreshToken}, "")
+	testutil.ExpectStatus(t, res, body, 401)
+
+	// The Legitimate Latest Token Is Revoked Too
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/token/refresh", user.RefreshTokenRequest{RefreshToken: second.RefreshToken}, "")
+	testutil.ExpectStatus(t, res, body, 401)
+}
+
+func TestRefreshTokenInvalid(t *testing.T) {
+	app := testutil.Setup(t)
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/token/refresh", user.RefreshTokenRequest{RefreshToken: "made-up"}, "")
+	testutil.ExpectStatus(t, res, body, 401)
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/token/refresh", user.RefreshTokenRequest{}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+}
+
+func TestRefreshTokenDisabledAccount(t *testing.T) {
+	app := testutil.Setup(t)
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/create", user.CreateUserRequest{Username: "tester", Email: "test@tester.com", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 201)
+	created := new(CreateUserResponse)
+	tokens := new(TokenResponse)
+	testutil.Decode(t, body, created)
+	testutil.Decode(t, body, tokens)
+
+	testutil.SetAccountEnabled(t, created.User.ID, false)
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/token/refresh", user.RefreshTokenRequest{RefreshToken: tokens.RefreshToken}, "")
+	testutil.ExpectStatus(t, res, body, 403)
+}
+
+func TestLogout(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+	tokens := new(TokenResponse)
+	testutil.Decode(t, body, tokens)
+	other := testutil.Login(t, app, "tester", "123")
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/logout", user.LogoutRequest{RefreshToken: tokens.RefreshToken}, "Bearer "+tokens.Token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Revoked Immediately
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer "+tokens.Token)
+	testutil.ExpectStatus(t, res, body, 401)
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/token/refresh", user.RefreshTokenRequest{RefreshToken: tokens.RefreshToken}, "")
+	testutil.ExpectStatus(t, res, body, 401)
+
+	// Other Sessions Are Untouched
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, other)
+	testutil.ExpectStatus(t, res, body, 200)
+}
+
+func TestLogoutAll(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+	tokens := new(TokenResponse)
+	testutil.Decode(t, body, tokens)
+	other := testutil.Login(t, app, "tester", "123")
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/logout-all", nil, other)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	for _, token := range []string{other, "Bearer " + tokens.Token} {
+		res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, token)
+		testutil.ExpectStatus(t, res, body, 401)
+	}
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/token/refresh", user.RefreshTokenRequest{RefreshToken: tokens.RefreshToken}, "")
+	testutil.ExpectStatus(t, res, body, 401)
+
+	// Logging In Again Works
+	fresh := testutil.Login(t, app, "tester", "123")
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, fresh)
+	testutil.ExpectStatus(t, res, body, 200)
+}
+
+// Revocations Made By Another Instance Are Read From The Database
+func TestLogoutSeenAcrossInstances(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/logout", nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// A Fresh Instance Has Nothing Cached
+	auth.ClearRevocationCache()
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, token)
+	testutil.ExpectStatus(t, res, body, 401)
+}
@@ -2,32 +2,30 @@ package user
 
 import (
 	"fmt"
-	"log"
 	"strconv"
 	"strings"
 	"time"
 
-	"app/api/auth"
+	"app/audit"
 	"app/config"
 	"app/database"
+	"app/logging"
+	"app/mail"
+	"app/metrics"
 
 	"app/util"
 
 	"github.com/gofiber/fiber/v2"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// Override By Reassignment
-var DEBUG = config.DEBUG
-
-// var DEBUG = false
-
 // Every User Must Be Assigned A Role
 type UserRole struct {
 	gorm.Model
 	Role        string `json:"role" gorm:"type:VARCHAR(32);unique;not null" validate:"omitempty"`
 	Description string `json:"description" gorm:"type:VARCHAR(100);" validate:"omitempty"`
+	// What Holders Of The Role May Do - See RequirePermission
+	Permissions []Permission `json:"permissions,omitempty" gorm:"many2many:role_permissions;" validate:"omitempty"`
 }
 
 type User struct {
@@ -36,9 +34,15 @@ type User struct {
 	Password       string   `json:"password" gorm:"type:VARCHAR(64);not null" validate:"omitempty,min=1,max=32"`
 	Email          string   `json:"email" gorm:"type:VARCHAR(48);not null;uniqueIndex:idx_email" validate:"required,email"`
 	Phone          string   `json:"phone" gorm:"type:VARCHAR(13)" validate:"omitempty,e164"`
-	AccountEnabled *bool    `json:"account_enabled" gorm:"default:1; not null" validate:"omitempty"`
+	AccountEnabled *bool    `json:"account_enabled" gorm:"default:true;not null" validate:"omitempty"`
 	RoleID         uint     `json:"role_id" validate:"omitempty,number"`
 	Role           UserRole `json:"user_role" validate:"omitempty"`
+	// Extra Roles On Top Of Role - Managed Through AdminUpdateUser
+	RoleGrants []UserRoleGrant `json:"role_grants,omitempty" validate:"omitempty"`
+	// Nil Until The User Follows The Emailed Link
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// Replaces Email Once Verified - Email Keeps Working Until Then
+	PendingEmail string `json:"pending_email" gorm:"type:VARCHAR(48)" validate:"omitempty,email"`
 }
 
 /*
@@ -62,65 +66,91 @@ func Login(c *fiber.Ctx) error {
 	r.Username = strings.ToLower(strings.TrimSpace(r.Username))
 	r.Password = strings.TrimSpace(r.Password)
 
-	if DEBUG {
-		log.Printf("Login -> Username: %s\n", r.Username)
-	}
+	logging.For(c).Debug("Login", "username", r.Username)
 
 	err = util.Validate(r)
 
 	// Validation
 	if err != nil {
-		if DEBUG {
-			log.Printf("Validation Error: %s\n", err.Error())
-		}
+		logging.For(c).Debug("Validation Error", "error", err)
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid Fields"})
 	}
 
+	// Slow Down Guessing From One Address
+	blocked, err := loginThrottle(c.UserContext(), addressTarget(c.IP()))
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	if blocked != nil {
+		return throttled(c, blocked)
+	}
+
 	// Create User
 	var user User
 	user.Username = r.Username
 
 	// Lookup User
-	err = database.DB.Preload("Role").Where("username = ?", r.Username).First(&user).Error
+	err = database.For(c).Preload("Role").Where("username = ?", r.Username).First(&user).Error
 	if err != nil {
-		if DEBUG {
-			log.Printf("Login Lookup Error: %s", err.Error())
-		}
+		logging.For(c).Debug("Login Lookup Error", "error", err)
 	}
 	// Check if User Exists
 	if user.ID == 0 {
-		if DEBUG {
-			log.Printf("Invalid Username: %s", user.Username)
-		}
+		logging.For(c).Info("Invalid Username", "username", user.Username)
+		loginFailed(c, r.Username, nil, "unknown_user")
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid Username or Password"})
 	}
+	// And Guessing At One Account
+	blocked, err = loginThrottle(c.UserContext(), accountTarget(user.ID))
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	if blocked != nil {
+		return throttled(c, blocked)
+	}
 	// Check If Account Is Enabled
 	if !*user.AccountEnabled {
-		if DEBUG {
-			log.Printf("Blocked Disabled Account: %s", user.Username)
-		}
+		logging.For(c).Info("Blocked Disabled Account", "username", user.Username)
+		auditLoginFailed(c, user.Username, &user, "account_disabled")
 		return c.Status(403).JSON(fiber.Map{"error": "User Account Disabled"})
 	}
 	// Check Users Password
-	pld := user.Username + r.Password + config.SALT
-	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(pld)) != nil {
-		if DEBUG {
-			log.Printf("Failed Login Attempt: %s\n", user.Username)
-		}
+	if !CheckPassword(c.UserContext(), &user, r.Password) {
+		logging.For(c).Info("Failed Login Attempt", "username", user.Username)
+		loginFailed(c, user.Username, &user, "password")
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid Username or Password"})
 	}
+	// Checked After The Password So It Doesn't Reveal Accounts
+	if config.Get().Auth.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		logging.For(c).Debug("Blocked Unverified Email", "username", user.Username)
+		return c.Status(403).JSON(fiber.Map{"error": "Email Not Verified"})
+	}
+	// Second Factor - Tokens Come From /user/login/mfa
+	enrolled, err := mfaEnabled(c.UserContext(), user.ID)
+	if err != nil {
+		logging.For(c).Error("Login MFA Lookup Error", "error", err)
+		return c.SendStatus(500)
+	}
+	if enrolled {
+		challenge, err := issueMFAChallenge(c.UserContext(), &user)
+		if err != nil {
+			return c.SendStatus(500)
+		}
+		return c.Status(200).JSON(fiber.Map{"mfa_required": true, "mfa_token": challenge})
+	}
 
-	// Create JWT Token For User
-	token, err := auth.IssueJWT(user.ID, user.Role.Role)
+	// Create JWT And Refresh Token For User
+	token, refreshToken, err := issueTokens(c.UserContext(), &user)
 
 	if err != nil {
-		if DEBUG {
-			log.Printf("Login JTW Error: %s\n", err.Error())
-		}
+		logging.For(c).Error("Login JTW Error", "error", err)
 		return c.SendStatus(500)
 	}
+	// Only A Full Login Clears Failures - A Second Factor Has To Pass First
+	clearLoginFailures(c.UserContext(), user.ID)
+	auditLogin(c, &user, "password")
 	user.Password = ""
-	return c.Status(200).JSON(fiber.Map{"token": token, "user": user})
+	return c.Status(200).JSON(fiber.Map{"token": token, "refresh_token": refreshToken, "user": user})
 }
 
 type CreateUserRequest struct {
@@ -134,9 +164,7 @@ func CreateUser(c *fiber.Ctx) error {
 	err := c.BodyParser(r)
 
 	if err != nil {
-		if DEBUG {
-			log.Printf("Create User Error: %s\n", err.Error())
-		}
+		logging.For(c).Debug("Create User Error", "error", err)
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
 	}
 
@@ -144,71 +172,108 @@ func CreateUser(c *fiber.Ctx) error {
 	err = util.Validate(r)
 	// Check Input
 	if err != nil {
-		if DEBUG {
-			log.Printf("Create User Error: %s\n", err.Error())
-		}
+		logging.For(c).Debug("Create User Error", "error", err)
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
 	}
 
 	var user User
 	user.Username = strings.TrimSpace(strings.ToLower(r.Username))
 	user.Password = strings.TrimSpace(r.Password)
-	user.RoleID = 1 // Assign Default Role
+	user.RoleID = DefaultRoleID
 	user.Email = strings.TrimSpace(r.Email)
 
 	// Hash Password
-	user.Password, err = HashPassword(user.Username, user.Password)
+	user.Password, err = HashPassword(c.UserContext(), user.Username, user.Password)
 	if err != nil {
-		if DEBUG {
-			log.Printf("Create User Error: Failed To Hash Password: %s\n", err.Error())
-		}
+		logging.For(c).Error("Create User Error: Failed To Hash Password", "error", err)
 		return c.SendStatus(500)
 	}
 	// Saving User To DB
-	err = database.DB.Create(&user).Error
+	err = database.For(c).Create(&user).Error
 	if err != nil || user.ID == 0 {
-		if DEBUG {
-			log.Printf("Create User Error: %s\n", err.Error())
-		}
+		logging.For(c).Debug("Create User Error", "error", err)
 		return c.Status(409).JSON(fiber.Map{"error": "Username or Email Already Exists"})
 	}
 	// Pulling Out Data
-	err = database.DB.Preload("Role").Omit("Password").First(&user).Error
+	err = database.For(c).Preload("Role").Omit("Password").First(&user).Error
 	if err != nil {
-		if DEBUG {
-			log.Printf("Create User Failed To Retrieve Data: %s", err.Error())
-		}
+		logging.For(c).Error("Create User Failed To Retrieve Data", "error", err)
 		return c.SendStatus(500)
 	}
-	// Create JWT Token For User
-	token, err := auth.IssueJWT(user.ID, user.Role.Role)
+	// Confirm The Address - Account Creation Doesn't Wait On It
+	err = sendVerification(c.UserContext(), &user, user.Email, c.AcceptsLanguages(mail.Locales()...))
+	if err != nil {
+		logging.For(c).Error("Create User: Failed To Queue Verification Email", "error", err)
+	}
+	// Create JWT And Refresh Token For User
+	token, refreshToken, err := issueTokens(c.UserContext(), &user)
 	// Check The Token Didn't Explode
 	if err != nil {
-		if DEBUG {
-			log.Printf("Create User: Failed To Generate Token: %s", err.Error())
-		}
+		logging.For(c).Error("Create User: Failed To Generate Token", "error", err)
 		return c.SendStatus(500)
 	}
+	audit.Record(c, audit.Entry{
+		Type:       audit.UserCreated,
+		ActorID:    user.ID,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		After:      auditUser(&user),
+	})
 	user.Password = ""
-	return c.Status(201).JSON(fiber.Map{"token": token, "user": user})
+	return c.Status(201).JSON(fiber.Map{"token": token, "refresh_token": refreshToken, "user": user})
+}
+
+// Audit Trail And Metrics For Logins - method Is password, mfa Or passkey
+func auditLogin(c *fiber.Ctx, user *User, method string) {
+	metrics.Logins.WithLabelValues(method).Inc()
+	audit.Record(c, audit.Entry{
+		Type:       audit.LoginSucceeded,
+		ActorID:    user.ID,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Metadata:   fiber.Map{"method": method},
+	})
+}
+
+// user Is Nil When Nobody Has The Username
+func auditLoginFailed(c *fiber.Ctx, username string, user *User, reason string) {
+	metrics.LoginFailures.WithLabelValues(reason).Inc()
+	entry := audit.Entry{
+		Type:     audit.LoginFailed,
+		Metadata: fiber.Map{"username": username, "reason": reason},
+	}
+	if user != nil {
+		entry.TargetType = audit.TargetUser
+		entry.TargetID = user.ID
+	}
+	audit.Record(c, entry)
+}
+
+// The Account Fields Audit Events Compare - Leaves Out Timestamps And Preloads
+func auditUser(user *User) fiber.Map {
+	return fiber.Map{
+		"username":          user.Username,
+		"email":             user.Email,
+		"pending_email":     user.PendingEmail,
+		"phone":             user.Phone,
+		"account_enabled":   user.AccountEnabled,
+		"role_id":           user.RoleID,
+		"email_verified_at": user.EmailVerifiedAt,
+	}
 }
 
 func GetUser(c *fiber.Ctx) error {
 	// Get UserID From Locals
 	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
 	if err != nil {
-		if DEBUG {
-			log.Printf("Get User Error: Failed Parsing Uint: %s\n", err.Error())
-		}
+		logging.For(c).Error("Get User Error: Failed Parsing Uint", "error", err)
 		return c.SendStatus(500)
 	}
 	var user User
 	user.ID = uint(user_id)
-	err = database.DB.Preload("Role").First(&user).Error
+	err = database.For(c).Preload("Role").First(&user).Error
 	if err != nil {
-		if DEBUG {
-			log.Printf("Get User Error: User Doesn't Exist: %s\n", err.Error())
-		}
+		logging.For(c).Debug("Get User Error: User Doesn't Exist", "error", err)
 		return c.Status(404).JSON(fiber.Map{"error": "User Doesn't Exist"})
 	}
 	user.Password = ""
@@ -224,9 +289,7 @@ func UpdateUser(c *fiber.Ctx) error {
 	// Get UserID From Locals
 	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
 	if err != nil {
-		if DEBUG {
-			log.Printf(`Update User Error Parsing Uint: %s\n`, err.Error())
-		}
+		logging.For(c).Error("Update User Error Parsing Uint", "error", err)
 		return c.SendStatus(500)
 	}
 
@@ -234,9 +297,7 @@ func UpdateUser(c *fiber.Ctx) error {
 	err = c.BodyParser(r)
 
 	if err != nil {
-		if DEBUG {
-			log.Printf("Update User Error: %s", err.Error())
-		}
+		logging.For(c).Debug("Update User Error", "error", err)
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
 	}
 
@@ -244,31 +305,50 @@ func UpdateUser(c *fiber.Ctx) error {
 	var user User
 	user.ID = uint(user_id)
 
-	err = database.DB.First(&user).Error
+	err = database.For(c).First(&user).Error
 	if user.ID == 0 || err != nil {
-		if DEBUG {
-			log.Printf("Update User: User Doesn't Exist: %s\n", err.Error())
-		}
+		logging.For(c).Debug("Update User: User Doesn't Exist", "error", err)
 		return c.Status(404).JSON(fiber.Map{"error": "User Doesn't Exist"})
 	}
 
-	user.Email = r.Email
+	before := auditUser(&user)
+
+	// A New Address Only Replaces The Old One Once Verified
+	r.Email = strings.TrimSpace(r.Email)
+	changedEmail := r.Email != "" && r.Email != user.Email
+	if changedEmail {
+		var taken int64
+		database.For(c).Model(&User{}).Where("email = ? AND id <> ?", r.Email, user.ID).Count(&taken)
+		if taken > 0 {
+			return c.Status(409).JSON(fiber.Map{"error": "Email Already Exists"})
+		}
+		user.PendingEmail = r.Email
+	}
 	user.Phone = r.Phone
 
 	// Try to save the new fields
-	err = database.DB.Save(&user).Error
+	err = database.For(c).Save(&user).Error
 	if err != nil {
-		if DEBUG {
-			log.Printf("Failed To Update User: %s\n", err.Error())
-		}
+		logging.For(c).Error("Failed To Update User", "error", err)
 		return c.SendStatus(500)
 	}
+	audit.Record(c, audit.Entry{
+		Type:       audit.UserUpdated,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Before:     before,
+		After:      auditUser(&user),
+	})
+	if changedEmail {
+		err = sendVerification(c.UserContext(), &user, user.PendingEmail, c.AcceptsLanguages(mail.Locales()...))
+		if err != nil {
+			logging.For(c).Error("Update User: Failed To Queue Verification Email", "error", err)
+		}
+	}
 	// Pull Out Updated User
-	err = database.DB.Preload("Role").Omit("Password").First(&user).Error
+	err = database.For(c).Preload("Role").Omit("Password").First(&user).Error
 	if err != nil {
-		if DEBUG {
-			log.Printf("Update User: Failed To Retrieve Data: %s\n", err.Error())
-		}
+		logging.For(c).Error("Update User: Failed To Retrieve Data", "error", err)
 		return c.SendStatus(500)
 	}
 	user.Password = ""
@@ -284,55 +364,44 @@ func UpdatePassword(c *fiber.Ctx) error {
 	// Get UserID From Locals
 	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
 	if err != nil {
-		if DEBUG {
-			log.Printf("Get User Error: Failed Parsing Uint: %s\n", err.Error())
-		}
+		logging.For(c).Error("Get User Error: Failed Parsing Uint", "error", err)
 		return c.SendStatus(500)
 	}
 	// Parse Request
 	r := new(UpdateUserPasswordRequest)
 	err = c.BodyParser(r)
 	if err != nil {
-		if DEBUG {
-			log.Printf("Update Password Error: %s\n", err.Error())
-		}
+		logging.For(c).Debug("Update Password Error", "error", err)
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
 	}
 
 	err = util.Validate(r)
 
 	if err != nil {
-		if DEBUG {
-			log.Printf("Update Password Error: %s\n", err.Error())
-		}
+		logging.For(c).Debug("Update Password Error", "error", err)
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
 	}
 
 	// Lookup User
 	var user User
-	err = database.DB.Where("id = ?", user_id).First(&user).Error
+	err = database.For(c).Where("id = ?", user_id).First(&user).Error
 	if err != nil {
-		if DEBUG {
-			log.Printf("Update Password Error: User Doesn't Exist: %s\n", err.Error())
-		}
+		logging.For(c).Debug("Update Password Error: User Doesn't Exist", "error", err)
 		return c.Status(404).JSON(fiber.Map{"error": "User Doesn't Exist"})
 	}
 	// Hash Password
-	user.Password, err = HashPassword(user.Username, r.Password)
+	user.Password, err = HashPassword(c.UserContext(), user.Username, r.Password)
 	if err != nil {
-		if DEBUG {
-			log.Printf("Update Password Error: %s\n", err.Error())
-		}
+		logging.For(c).Error("Update Password Error", "error", err)
 		return c.SendStatus(500)
 	}
 	// Saving User To DB
-	err = database.DB.Save(&user).Error
+	err = database.For(c).Save(&user).Error
 	if err != nil {
-		if DEBUG {
-			log.Printf("Update Password Error: Failed To Save: %s\n", err.Error())
-		}
+		logging.For(c).Error("Update Password Error: Failed To Save", "error", err)
 		return c.SendStatus(500)
 	}
+	audit.Record(c, audit.Entry{Type: audit.PasswordChanged, TargetType: audit.TargetUser, TargetID: user.ID})
 	return c.SendStatus(200)
 }
 
@@ -341,22 +410,19 @@ func DeleteUser(c *fiber.Ctx) error {
 	// Get UserID From Locals
 	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
 	if err != nil {
-		if DEBUG {
-			log.Printf("Get User Error: Failed Parsing Uint: %s\n", err.Error())
-		}
+		logging.For(c).Error("Get User Error: Failed Parsing Uint", "error", err)
 		return c.SendStatus(500)
 	}
 
 	var user User
 	user.ID = uint(user_id)
 
-	err = database.DB.Delete(&user).Error
+	err = database.For(c).Delete(&user).Error
 	if err != nil {
-		if DEBUG {
-			log.Printf("Delete User Error: User Doesn't Exist: %s\n", err.Error())
-		}
+		logging.For(c).Debug("Delete User Error: User Doesn't Exist", "error", err)
 		return c.Status(404).JSON(fiber.Map{"error": "User Doesn't Exist"})
 	}
+	audit.Record(c, audit.Entry{Type: audit.UserDeleted, TargetType: audit.TargetUser, TargetID: user.ID})
 
 	return c.SendStatus(200)
 }
@@ -377,6 +443,9 @@ type AdminUserUpdateRequest struct {
 	Email           string `json:"email" validate:"omitempty,email"`
 	Phone           string `json:"phone" validate:"omitempty,e164"`
 	Account_enabled *bool  `json:"account_enabled" validate:"omitempty"`
+	// Granting A Role The User Already Has Just Changes When It Expires
+	AddRoles    []RoleGrantRequest `json:"add_roles" validate:"omitempty,dive"`
+	RemoveRoles []uint             `json:"remove_roles" validate:"omitempty"`
 }
 
 // Allows a User To Update Their Settings
@@ -386,9 +455,7 @@ func AdminUpdateUser(c *fiber.Ctx) error {
 	err := c.BodyParser(r)
 
 	if err != nil {
-		if DEBUG {
-			log.Println("Admin Update: ", err)
-		}
+		logging.For(c).Debug("Admin Update Error: Invalid Input", "error", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Error Parsing Input"})
 	}
 
@@ -396,45 +463,120 @@ func AdminUpdateUser(c *fiber.Ctx) error {
 	err = util.Validate(r)
 	// Check Input
 	if err != nil {
-		log.Println(err)
+		logging.For(c).Debug("Admin Update Error: Failed Validation", "error", err)
 		return c.Status(400).JSON(fiber.Map{"error": "Failed Validation"})
 	}
 
 	// Lookup Record
 	var user User
-	database.DB.First(&user, r.UserID)
+	database.For(c).First(&user, r.UserID)
 	if user.ID == 0 {
-		if DEBUG {
-			log.Printf("Admin Update Error: User Doesn't Exist: %s", err.Error())
-		}
+		logging.For(c).Debug("Admin Update Error: User Doesn't Exist", "error", err)
 		return c.Status(404).JSON(fiber.Map{"error": "User Doesn't Exist"})
 	}
 
+	// Handing Out Roles Is Role Management - users:write Alone Could Grant admin
+	if r.RoleID != user.RoleID || len(r.AddRoles) != 0 || len(r.RemoveRoles) != 0 {
+		missing, err := missingPermission(c, PermRolesWrite)
+		if err != nil {
+			logging.For(c).Error("Admin Update Error", "error", err)
+			return c.SendStatus(500)
+		}
+		if missing != "" {
+			logging.For(c).Info("Permission Denied", "user_id", c.Locals("user_id"), "permission", missing)
+			return c.Status(403).JSON(fiber.Map{"error": "Missing Permission: " + missing})
+		}
+	}
+
+	// Check The Roles Before Changing Anything
+	roleIDs := []uint{r.RoleID}
+	now := time.Now()
+	for _, grant := range r.AddRoles {
+		if grant.ExpiresAt != nil && !grant.ExpiresAt.After(now) {
+			return c.Status(400).JSON(fiber.Map{"error": "Role Grants Must Expire In The Future"})
+		}
+		roleIDs = append(roleIDs, grant.RoleID)
+	}
+	for _, roleID := range roleIDs {
+		var count int64
+		err = database.For(c).Model(&UserRole{}).Where("id = ?", roleID).Count(&count).Error
+		if err != nil {
+			return c.SendStatus(500)
+		}
+		if count == 0 {
+			return c.Status(404).JSON(fiber.Map{"error": errRoleNotFound.Error()})
+		}
+	}
+	admin_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+
+	// Recorded With The Grants So Role Changes Show Up In The Diff
+	before := auditUser(&user)
+	err = database.For(c).Where("user_id = ?", user.ID).Find(&user.RoleGrants).Error
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	before["role_grants"] = auditGrants(user.RoleGrants)
+	user.RoleGrants = nil
+
 	user.ID = r.UserID
-	user.AccountEnabled = r.Account_enabled
-	user.Email = r.Email
+	if r.Account_enabled != nil {
+		user.AccountEnabled = r.Account_enabled
+	}
+	// An Address The Admin Sets Still Has To Be Confirmed By Its Owner
+	changedEmail := r.Email != "" && r.Email != user.Email
+	if changedEmail {
+		user.Email = r.Email
+		user.EmailVerifiedAt = nil
+		user.PendingEmail = ""
+	}
 	user.Phone = r.Phone
 	user.RoleID = r.RoleID
 	user.UpdatedAt = time.Now().Local()
 
 	// Try to save the new fields
-	err = database.DB.Save(&user).Error
+	err = database.For(c).Transaction(func(tx *gorm.DB) error {
+		err := tx.Save(&user).Error
+		if err != nil {
+			return err
+		}
+		return changeRoleGrants(tx, user.ID, uint(admin_id), r.AddRoles, r.RemoveRoles)
+	})
 	if err != nil {
-		c.Status(500).JSON(fiber.Map{"error": "Failed To Update"})
+		logging.For(c).Error("Admin Update Error", "error", err)
+		return c.Status(500).JSON(fiber.Map{"error": "Failed To Update"})
 	}
+	// The Roles May Have Changed
+	ClearPermissionCache()
 	// Pull Out Updated User
-	database.DB.Preload("Role").First(&user)
+	database.For(c).Preload("Role").Preload("RoleGrants.Role").First(&user)
+
+	after := auditUser(&user)
+	after["role_grants"] = auditGrants(user.RoleGrants)
+	audit.Record(c, audit.Entry{
+		Type:       audit.AdminUserUpdated,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Before:     before,
+		After:      after,
+	})
+	if changedEmail {
+		err = sendVerification(c.UserContext(), &user, user.Email, c.AcceptsLanguages(mail.Locales()...))
+		if err != nil {
+			logging.For(c).Error("Admin Update Error: Failed To Queue Verification Email", "error", err)
+		}
+	}
 	user.Password = ""
 	return c.Status(200).JSON(fiber.Map{"user": user})
 }
 
 func GetAll(c *fiber.Ctx) error {
 	var users []User
-	err := database.DB.Preload("Role").Omit("Password").Find(&users).Error
+	err := database.For(c).Preload("Role").Omit("Password").Find(&users).Error
 	if err != nil {
-		if DEBUG {
-			log.Printf("Get All Users: %s", err.Error())
-		}
+		logging.For(c).Error("Get All Users", "error", err)
 		return c.SendStatus(500)
 	}
 	return c.Status(200).JSON(fiber.Map{"users": users})
@@ -442,11 +584,9 @@ func GetAll(c *fiber.Ctx) error {
 
 func GetUserRoles(c *fiber.Ctx) error {
 	var userRoles []UserRole
-	err := database.DB.Find(&userRoles).Error
+	err := database.For(c).Preload("Permissions").Find(&userRoles).Error
 	if err != nil {
-		if DEBUG {
-			log.Printf("Get User Roles Error: %s\n", err.Error())
-		}
+		logging.For(c).Error("Get User Roles Error", "error", err)
 		return c.Status(500).JSON(fiber.Map{"error": "Failed To Retrieve User Roles"})
 	}
 	return c.Status(200).JSON(fiber.Map{"user_roles": userRoles})
@@ -1,300 +1,364 @@
-package user
+package user_test
 
 import (
-	"log"
+	"context"
+	"net/http"
 	"testing"
 
-	"github.com/goccy/go-json"
 	"github.com/gofiber/fiber/v2"
-)
-
-const URL = "http://localhost:5000/user"
 
-var TOKEN = "Bearer: "
+	"app/models/user"
+	"app/testutil"
+)
 
 // Create User And Login Response
 type CreateUserResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token string    `json:"token"`
+	User  user.User `json:"user"`
 }
 
 type UserResponse struct {
-	User User `json:"user"`
+	User user.User `json:"user"`
 }
 
 func TestCreateUser(t *testing.T) {
-	agent := fiber.Post(URL + "/create")
-	if DEBUG {
-		log.Println("Running Create User Test")
-		agent.Debug()
-	}
+	app := testutil.Setup(t)
 
 	// Create New Request
-	req := new(CreateUserRequest)
-	req.Username = "tester"
+	req := new(user.CreateUserRequest)
+	req.Username = "Tester"
 	req.Password = "123"
 	req.Email = "test@tester.com"
 
-	// Send JSON Request
-	agent.JSON(req)
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/create", req, "")
+	testutil.ExpectStatus(t, res, body, 201)
 
-	// Destructure Response
-	statusCode, body, errs := agent.Bytes()
+	created := new(CreateUserResponse)
+	testutil.Decode(t, body, created)
 
-	expectedStatus := 201
-	if statusCode != expectedStatus {
-		t.Fatalf("\nInvalid Status Code: %d Expected: %d\n", statusCode, expectedStatus)
+	// Usernames Are Lowercased
+	if created.User.Username != "tester" {
+		t.Fatalf("\nFailed To Create User: %s\n", created.User.Username)
 	}
-
-	// Parse JSON
-	res := new(CreateUserResponse)
-	err := json.Unmarshal(body, res)
-
-	if err != nil {
-		t.Fatalf("\nFailed To Unmarshal JSON\n")
+	if created.User.Password != "" {
+		t.Fatalf("\nPassword Hash Leaked In Response\n")
 	}
-
-	if len(errs) > 0 {
-		t.Fatalf("\nFailed: %v+\n", errs)
+	if created.User.Role.Role != "default" {
+		t.Fatalf("\nInvalid Role: %s Expected: default\n", created.User.Role.Role)
 	}
-
-	// Expecting Username = tester
-	if req.Username != res.User.Username {
-		t.Fatalf("\nFailed To Create User: %s\n", req.Username)
+	if created.Token == "" {
+		t.Fatalf("\nMissing Token\n")
 	}
 
+	// The Returned Token Works Straight Away
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer "+created.Token)
+	testutil.ExpectStatus(t, res, body, 200)
 }
 
-func TestLogin(t *testing.T) {
-	agent := fiber.Post(URL + "/login")
-	if DEBUG {
-		log.Println("Running Login Test")
-		agent.Debug()
-	}
+func TestCreateUserDuplicate(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
 
-	req := new(LoginRequest)
-	req.Username = "tester"
-	req.Password = "123"
+	req := user.CreateUserRequest{Username: "tester", Email: "other@tester.com", Password: "123"}
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/create", req, "")
+	testutil.ExpectStatus(t, res, body, 409)
+}
 
-	agent.JSON(req)
+func TestCreateUserInvalid(t *testing.T) {
+	app := testutil.Setup(t)
 
-	// Destructure Response
-	statusCode, body, errs := agent.Bytes()
+	req := user.CreateUserRequest{Username: "tester", Email: "not-an-email", Password: "123"}
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/create", req, "")
+	testutil.ExpectStatus(t, res, body, 400)
+}
 
-	expectedStatus := 200
-	if statusCode != expectedStatus {
-		t.Fatalf("\nInvalid Status Code: %d Expected: %d\n", statusCode, expectedStatus)
-	}
+func TestLogin(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
 
-	// Parse JSON
-	res := new(CreateUserResponse)
-	err := json.Unmarshal(body, res)
+	req := user.LoginRequest{Username: "tester", Password: "123"}
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/login", req, "")
+	testutil.ExpectStatus(t, res, body, 200)
 
-	// Set Token For Other Tests
-	TOKEN += res.Token
+	login := new(CreateUserResponse)
+	testutil.Decode(t, body, login)
 
-	if err != nil {
-		t.Fatalf("\nFailed To Unmarshal JSON\n")
+	if login.User.Username != req.Username {
+		t.Fatalf("\nFailed To Login: %s\n", req.Username)
 	}
-
-	if len(errs) > 0 {
-		t.Fatalf("\nFailed: %v+\n", errs)
+	if login.Token == "" {
+		t.Fatalf("\nMissing Token\n")
 	}
+}
 
-	// Expecting Username = tester
-	if req.Username != res.User.Username {
-		t.Fatalf("\nFailed To Login: %s\n", req.Username)
+func TestLoginFailures(t *testing.T) {
+	app := testutil.Setup(t)
+	disabled := testutil.CreateUser(t, "disabled", "123", "default")
+	testutil.CreateUser(t, "tester", "123", "default")
+
+	testutil.SetAccountEnabled(t, disabled.ID, false)
+
+	cases := []struct {
+		name     string
+		req      user.LoginRequest
+		expected int
+	}{
+		{"Wrong Password", user.LoginRequest{Username: "tester", Password: "1234"}, 400},
+		{"Unknown Username", user.LoginRequest{Username: "nobody", Password: "123"}, 400},
+		{"Missing Password", user.LoginRequest{Username: "tester"}, 400},
+		{"Disabled Account", user.LoginRequest{Username: "disabled", Password: "123"}, 403},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, body := testutil.Request(t, app, http.MethodPost, "/user/login", tc.req, "")
+			testutil.ExpectStatus(t, res, body, tc.expected)
+		})
 	}
 }
 
 func TestGet(t *testing.T) {
-	agent := fiber.Get(URL)
-	if DEBUG {
-		log.Println("Running Get Test")
-		// log.Printf("TOKEN = %s\n", TOKEN)
-		agent.Debug()
-	}
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
 
-	agent.Set("Authorization", TOKEN)
+	res, body := testutil.Request(t, app, http.MethodGet, "/user/", nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
 
-	// Destructure Response
-	statusCode, body, errs := agent.Bytes()
+	got := new(UserResponse)
+	testutil.Decode(t, body, got)
 
-	expectedStatus := 200
-	if statusCode != expectedStatus {
-		t.Fatalf("\nInvalid Status Code: %d Expected: %d\n", statusCode, expectedStatus)
+	// Expecting Username = tester
+	if got.User.Username != "tester" {
+		t.Fatalf("\nFailed To Get User: %s\n", got.User.Username)
 	}
-
-	res := new(UserResponse)
-	err := json.Unmarshal(body, res)
-
-	if err != nil {
-		t.Fatalf("\nFailed To Unmarshal JSON\n")
+	if got.User.Password != "" {
+		t.Fatalf("\nPassword Hash Leaked In Response\n")
 	}
+}
+
+func TestRequiresToken(t *testing.T) {
+	app := testutil.Setup(t)
 
-	if len(errs) > 0 {
-		t.Fatalf("\nFailed: %v+\n", errs)
+	routes := []struct{ method, path string }{
+		{http.MethodGet, "/user/"},
+		{http.MethodPut, "/user/update-user"},
+		{http.MethodPut, "/user/update-password"},
+		{http.MethodDelete, "/user/"},
+		{http.MethodPut, "/user/admin-user-update"},
+		{http.MethodGet, "/user/getall"},
+		{http.MethodGet, "/user/get-user-roles"},
 	}
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			res, body := testutil.Request(t, app, route.method, route.path, nil, "")
+			testutil.ExpectStatus(t, res, body, 401)
 
-	// Expecting Username = tester
-	expectedUsername := "tester"
-	if expectedUsername != res.User.Username {
-		t.Fatalf("\nFailed To Get User: %s\n", expectedUsername)
+			res, body = testutil.Request(t, app, route.method, route.path, nil, "Bearer not.a.token")
+			testutil.ExpectStatus(t, res, body, 401)
+		})
 	}
 }
 
 func TestUpdateUser(t *testing.T) {
-	agent := fiber.Put(URL + "/update-user")
-	if DEBUG {
-		log.Println("Running Update User Test")
-		agent.Debug()
-	}
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
 
-	agent.Request().Header.Add("Authorization", TOKEN)
+	req := user.UserUpdateRequest{Email: "testerupdated@tester.com", Phone: "+19998675309"}
+	res, body := testutil.Request(t, app, http.MethodPut, "/user/update-user", req, token)
+	testutil.ExpectStatus(t, res, body, 200)
 
-	req := new(User)
-	req.Email = "testerupdated@tester.com"
-	req.Phone = "+19998675309"
+	updated := new(UserResponse)
+	testutil.Decode(t, body, updated)
 
-	agent.JSON(req)
-
-	// Destructure Response
-	statusCode, body, errs := agent.Bytes()
-
-	expectedStatus := 200
-	if statusCode != expectedStatus {
-		t.Fatalf("\nInvalid Status Code: %d Expected: %d\n", statusCode, expectedStatus)
+	// The New Email Waits On Verification (See TestEmailChangeVerification)
+	if updated.User.PendingEmail != req.Email || updated.User.Phone != req.Phone {
+		t.Fatalf("\nFailed To Update: %s %s\n", updated.User.PendingEmail, updated.User.Phone)
 	}
+	if updated.User.Email != "tester@tester.com" {
+		t.Fatalf("\nEmail Changed Before Verification: %s\n", updated.User.Email)
+	}
+}
 
-	// Parse JSON
-	res := new(UserResponse)
-	err := json.Unmarshal(body, res)
+func TestUpdatePassword(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
 
-	if err != nil {
-		t.Fatalf("\nFailed To Unmarshal JSON\n")
-	}
+	req := user.UpdateUserPasswordRequest{Password: "1234"}
+	res, body := testutil.Request(t, app, http.MethodPut, "/user/update-password", req, token)
+	testutil.ExpectStatus(t, res, body, 200)
 
-	if len(errs) > 0 {
-		t.Fatalf("\nFailed: %v+\n", errs)
-	}
+	// Old Password Is Expected To Fail
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 400)
 
-	// Expecting Username = tester
-	if req.Email != res.User.Email {
-		t.Fatalf("\nFailed To Update: %s\n", req.Username)
-	}
+	// New Password Is Expected To Pass
+	testutil.Login(t, app, "tester", "1234")
 }
 
-func TestUpdatePassword(t *testing.T) {
-	agent := fiber.Put(URL + "/update-password")
-	if DEBUG {
-		log.Println("Running Update Password Test")
-		agent.Debug()
-	}
+// Tests Soft Delete Functionality
+func TestDeleteUser(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
 
-	agent.Request().Header.Add("Authorization", TOKEN)
+	res, body := testutil.Request(t, app, http.MethodDelete, "/user/", nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
 
-	req := new(UpdateUserPasswordRequest)
-	req.Password = "1234"
+	// Deleted Accounts Can't Use Their Token Or Login
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, token)
+	testutil.ExpectStatus(t, res, body, 401)
 
-	agent.JSON(req)
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+}
 
-	statusCode, _, errs := agent.Bytes()
+func TestDisabledAccountBlocked(t *testing.T) {
+	app := testutil.Setup(t)
+	u := testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
 
-	expectedStatus := 200
-	if statusCode != expectedStatus {
-		t.Fatalf("\nInvalid Status Code: %d Expected: %d\n", statusCode, expectedStatus)
-	}
+	testutil.SetAccountEnabled(t, u.ID, false)
 
-	if len(errs) > 0 {
-		t.Fatalf("\nFailed: %v+\n", errs)
-	}
+	res, body := testutil.Request(t, app, http.MethodGet, "/user/", nil, token)
+	testutil.ExpectStatus(t, res, body, 403)
 }
 
-// Expected To Fail
-func TestUpdatedPassword_LoginFail(t *testing.T) {
-	agent := fiber.Post(URL + "/login")
-	if DEBUG {
-		log.Println("Running Updated Password Login Fail Test")
-		agent.Debug()
-	}
+/*
+	Admin Functions
+*/
 
-	req := new(LoginRequest)
-	req.Username = "tester"
-	req.Password = "123"
+func TestAdminRoutesForbidden(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
 
-	agent.JSON(req)
+	routes := []struct{ method, path string }{
+		{http.MethodPut, "/user/admin-user-update"},
+		{http.MethodGet, "/user/getall"},
+		{http.MethodGet, "/user/get-user-roles"},
+	}
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			res, body := testutil.Request(t, app, route.method, route.path, nil, token)
+			testutil.ExpectStatus(t, res, body, 403)
+		})
+	}
+}
 
-	// Destructure Response
-	statusCode, _, errs := agent.Bytes()
+func TestAdminUpdateUser(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "admin", "123", "admin")
+	target := testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "admin", "123")
 
-	expectedStatus := 400
-	if statusCode != expectedStatus {
-		t.Fatalf("\nInvalid Status Code: %d Expected: %d\n", statusCode, expectedStatus)
+	enabled := false
+	req := user.AdminUserUpdateRequest{
+		UserID:          target.ID,
+		RoleID:          testutil.Role(t, "admin").ID,
+		Email:           "changed@tester.com",
+		Account_enabled: &enabled,
 	}
+	res, body := testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, token)
+	testutil.ExpectStatus(t, res, body, 200)
 
-	if len(errs) > 0 {
-		t.Fatalf("\nFailed: %v+\n", errs)
-	}
-}
+	updated := new(UserResponse)
+	testutil.Decode(t, body, updated)
 
-// Expected To Pass
-func TestUpdatedPassword_LoginPass(t *testing.T) {
-	agent := fiber.Post(URL + "/login")
-	if DEBUG {
-		log.Println("Running Updated Password Login Pass Test")
-		agent.Debug()
+	if updated.User.Role.Role != "admin" || updated.User.Email != req.Email || *updated.User.AccountEnabled {
+		t.Fatalf("\nFailed To Update User: %+v\n", updated.User)
 	}
 
-	req := new(LoginRequest)
-	req.Username = "tester"
-	req.Password = "1234"
+	// Disabled By The Admin
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 403)
 
-	agent.JSON(req)
+	// Unknown Users
+	req.UserID = 999
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, token)
+	testutil.ExpectStatus(t, res, body, 404)
+}
 
-	// Destructure Response
-	statusCode, body, errs := agent.Bytes()
+// users:write Without roles:write Can Edit Accounts But Not Hand Out Roles
+func TestAdminUpdateUserRoles(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "admin", "123", "admin")
+	admin := testutil.Login(t, app, "admin", "123")
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/roles", user.CreateRoleRequest{Role: "support", Permissions: []string{user.PermUsersWrite}}, admin)
+	testutil.ExpectStatus(t, res, body, 201)
+	helper := testutil.CreateUser(t, "helper", "123", "support")
+	token := testutil.Login(t, app, "helper", "123")
+	adminRole := testutil.Role(t, "admin").ID
+
+	// Not Even To Themselves
+	req := user.AdminUserUpdateRequest{UserID: helper.ID, RoleID: adminRole}
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, token)
+	testutil.ExpectStatus(t, res, body, 403)
+	req = user.AdminUserUpdateRequest{UserID: helper.ID, RoleID: helper.RoleID, AddRoles: []user.RoleGrantRequest{{RoleID: adminRole}}}
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, token)
+	testutil.ExpectStatus(t, res, body, 403)
+
+	// Leaving The Roles Alone Is Fine
+	req = user.AdminUserUpdateRequest{UserID: helper.ID, RoleID: helper.RoleID, Phone: "+15555550100"}
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	granted, err := user.RoleNames(context.Background(), helper.ID)
+	if err != nil || len(granted) != 1 || granted[0] != "support" {
+		t.Fatalf("\nUnexpected Roles: %v %v\n", granted, err)
+	}
+
+	// Unknown Roles
+	req = user.AdminUserUpdateRequest{UserID: helper.ID, RoleID: 999}
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, admin)
+	testutil.ExpectStatus(t, res, body, 404)
+}
 
-	expectedStatus := 200
-	if statusCode != expectedStatus {
-		t.Fatalf("\nInvalid Status Code: %d Expected: %d\n", statusCode, expectedStatus)
-	}
+func TestGetAll(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "admin", "123", "admin")
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "admin", "123")
 
-	// Parse JSON
-	res := new(CreateUserResponse)
-	err := json.Unmarshal(body, res)
+	res, body := testutil.Request(t, app, http.MethodGet, "/user/getall", nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
 
-	if err != nil {
-		t.Fatalf("\nFailed To Unmarshal JSON\n")
+	var all struct {
+		Users []user.User `json:"users"`
 	}
+	testutil.Decode(t, body, &all)
 
-	if len(errs) > 0 {
-		t.Fatalf("\nFailed: %v+\n", errs)
+	if len(all.Users) != 2 {
+		t.Fatalf("\nInvalid User Count: %d Expected: 2\n", len(all.Users))
 	}
-
-	// Expecting Username = tester
-	if req.Username != res.User.Username {
-		t.Fatalf("\nFailed To Login: %s\n", req.Username)
+	for _, u := range all.Users {
+		if u.Password != "" {
+			t.Fatalf("\nPassword Hash Leaked For %s\n", u.Username)
+		}
 	}
 }
 
-// Tests Soft Delete Functionality
-func TestDeleteUser(t *testing.T) {
-	agent := fiber.Delete(URL + "/")
-	if DEBUG {
-		log.Println("Running Delete User Test")
-		agent.Debug()
-	}
-
-	agent.Request().Header.Add("Authorization", TOKEN)
+func TestGetUserRoles(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "admin", "123", "admin")
+	token := testutil.Login(t, app, "admin", "123")
 
-	statusCode, _, errs := agent.Bytes()
+	res, body := testutil.Request(t, app, http.MethodGet, "/user/get-user-roles", nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
 
-	expectedStatus := 200
-	if statusCode != expectedStatus {
-		t.Fatalf("\nInvalid Status Code: %d Expected: %d\n", statusCode, expectedStatus)
+	var roles struct {
+		UserRoles []user.UserRole `json:"user_roles"`
 	}
+	testutil.Decode(t, body, &roles)
 
-	if len(errs) > 0 {
-		t.Fatalf("\nFailed: %v+\n", errs)
+	if len(roles.UserRoles) != 2 {
+		t.Fatalf("\nInvalid Role Count: %d Expected: 2\n", len(roles.UserRoles))
 	}
 }
+
+func TestHealth(t *testing.T) {
+	app := testutil.Setup(t)
+
+	res, body := testutil.Request(t, app, http.MethodGet, "/", nil, "")
+	testutil.ExpectStatus(t, res, body, fiber.StatusOK)
+}
@@ -2,20 +2,29 @@ package user
 
 import (
 	"app/config"
+	"app/metrics"
+	"app/tracing"
+	"context"
 	"errors"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // Takes A User - Returns Password Hash and Error
-func HashPassword(username string, password string) (string, error) {
+func HashPassword(ctx context.Context, username string, password string) (string, error) {
 	if username == "" {
 		return "", errors.New("Invalid Username")
 	}
 	if password == "" {
 		return "", errors.New("Invalid Password")
 	}
-	pld := username + password + config.SALT
+	_, span := tracing.Start(ctx, "bcrypt.hash")
+	defer span.End()
+	timer := prometheus.NewTimer(metrics.PasswordHashDuration.WithLabelValues(metrics.PasswordHash))
+	defer timer.ObserveDuration()
+
+	pld := username + password + config.Get().Auth.Salt
 	bytes, err := bcrypt.GenerateFromPassword([]byte(pld), 7)
 	if err != nil {
 		return "", err
@@ -23,3 +32,14 @@ func HashPassword(username string, password string) (string, error) {
 	hash := string(bytes)
 	return hash, nil
 }
+
+// True When password Matches The User's Hash
+func CheckPassword(ctx context.Context, user *User, password string) bool {
+	_, span := tracing.Start(ctx, "bcrypt.compare")
+	defer span.End()
+	timer := prometheus.NewTimer(metrics.PasswordHashDuration.WithLabelValues(metrics.PasswordCompare))
+	defer timer.ObserveDuration()
+
+	pld := user.Username + password + config.Get().Auth.Salt
+	return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(pld)) == nil
+}
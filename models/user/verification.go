@@ -0,0 +1,166 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/api/auth"
+	"app/audit"
+	"app/config"
+	"app/database"
+	"app/logging"
+	"app/mail"
+	"app/util"
+)
+
+/*
+Verification Links Carry A Signed Token For The Email Verification Audience
+Naming The Address - Nothing Is Stored, A Link Stops Working Once Its
+Address Is No Longer The User's Unverified Or Pending Email
+*/
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// Confirms An Address - A Pending Email Replaces The Current One
+func VerifyEmail(c *fiber.Ctx) error {
+	r := new(VerifyEmailRequest)
+	err := c.BodyParser(r)
+
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	invalid := fiber.Map{"error": "Invalid Or Expired Verification Link"}
+
+	claims, err := auth.ParseToken(r.Token, auth.AudienceEmailVerification)
+	if errors.Is(err, auth.ErrKeysUnavailable) {
+		logging.For(c).Error("Verify Email Error", "error", err)
+		return c.SendStatus(500)
+	}
+	if err != nil {
+		logging.For(c).Debug("Verify Email Error", "error", err)
+		return c.Status(400).JSON(invalid)
+	}
+	user_id, err := claims.UserID()
+	if err != nil {
+		return c.Status(400).JSON(invalid)
+	}
+
+	var user User
+	err = database.For(c).First(&user, user_id).Error
+	if err != nil {
+		return c.Status(400).JSON(invalid)
+	}
+
+	before := auditUser(&user)
+	now := time.Now()
+	switch {
+	case claims.Email != "" && claims.Email == user.PendingEmail:
+		// Someone May Have Registered The Address Since The Change Was Requested
+		var taken int64
+		database.For(c).Model(&User{}).Where("email = ? AND id <> ?", user.PendingEmail, user.ID).Count(&taken)
+		if taken > 0 {
+			return c.Status(409).JSON(fiber.Map{"error": "Email Already Exists"})
+		}
+		user.Email = user.PendingEmail
+		user.PendingEmail = ""
+	case claims.Email != "" && claims.Email == user.Email && user.EmailVerifiedAt == nil:
+	default:
+		return c.Status(400).JSON(invalid)
+	}
+	user.EmailVerifiedAt = &now
+
+	err = database.For(c).Model(&user).Select("email", "pending_email", "email_verified_at").Updates(&user).Error
+	if err != nil {
+		logging.For(c).Error("Verify Email Error: Failed To Save", "error", err)
+		return c.SendStatus(500)
+	}
+	audit.Record(c, audit.Entry{
+		Type:       audit.EmailVerified,
+		ActorID:    user.ID,
+		TargetType: audit.TargetUser,
+		TargetID:   user.ID,
+		Before:     before,
+		After:      auditUser(&user),
+	})
+	return c.Status(200).JSON(fiber.Map{"email": user.Email, "email_verified_at": user.EmailVerifiedAt})
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+/*
+Sends A Fresh Link To An Unverified Or Pending Address
+Public As Unverified Users May Not Be Able To Login - Always 202
+*/
+func ResendVerification(c *fiber.Ctx) error {
+	r := new(ResendVerificationRequest)
+	err := c.BodyParser(r)
+
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+
+	r.Email = strings.TrimSpace(r.Email)
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	accepted := fiber.Map{"message": "If That Email Needs Verifying A Link Is On Its Way"}
+
+	var user User
+	err = database.For(c).
+		Where("(email = ? AND email_verified_at IS NULL) OR pending_email = ?", r.Email, r.Email).
+		First(&user).Error
+	if err != nil {
+		logging.For(c).Debug("Resend Verification: Nothing To Verify", "email", r.Email)
+		return c.Status(202).JSON(accepted)
+	}
+
+	err = sendVerification(c.UserContext(), &user, r.Email, c.AcceptsLanguages(mail.Locales()...))
+	if err != nil {
+		logging.For(c).Error("Resend Verification: Failed To Queue Email", "error", err)
+	}
+	return c.Status(202).JSON(accepted)
+}
+
+// Queues A Verification Link For email To The User
+func sendVerification(ctx context.Context, user *User, email string, locale string) error {
+	cfg := config.Get()
+	ttl := time.Duration(cfg.Auth.EmailVerificationExpires) * time.Second
+
+	claims, err := auth.NewClaims(user.ID, auth.AudienceEmailVerification, ttl)
+	if err != nil {
+		return err
+	}
+	claims.Email = email
+	keys, err := auth.Keys()
+	if err != nil {
+		return err
+	}
+	token, err := keys.Sign(claims)
+	if err != nil {
+		return err
+	}
+
+	return mail.Queue(ctx, email, "verify_email", locale, fiber.Map{
+		"Username": user.Username,
+		"Link":     strings.TrimSuffix(cfg.App.FrontendURL, "/") + "/verify-email?token=" + url.QueryEscape(token),
+		"Hours":    cfg.Auth.EmailVerificationExpires / 3600,
+		"Changing": email != user.Email,
+	})
+}
@@ -0,0 +1,160 @@
+package user_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"app/api/auth"
+	"app/database"
+	"app/models/user"
+	"app/testutil"
+)
+
+func TestSignupEmailVerification(t *testing.T) {
+	cfg := testutil.Config(t)
+	cfg.Auth.RequireVerifiedEmail = true
+	app := testutil.SetupWith(t, cfg)
+
+	req := user.CreateUserRequest{Username: "tester", Email: "tester@tester.com", Password: "123"}
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/create", req, "")
+	testutil.ExpectStatus(t, res, body, 201)
+
+	// Login Waits On Verification
+	login := user.LoginRequest{Username: "tester", Password: "123"}
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login", login, "")
+	testutil.ExpectStatus(t, res, body, 403)
+
+	// But Not With The Wrong Password - That Would Reveal The Account
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "wrong"}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+
+	token := testutil.LinkParam(t, testutil.LastMail(t, req.Email), "token")
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/email/verify", user.VerifyEmailRequest{Token: token}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+
+	testutil.Login(t, app, "tester", "123")
+
+	// Verifying Again Does Nothing
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/email/verify", user.VerifyEmailRequest{Token: token}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+}
+
+// The Old Address Stays Until The New One Is Confirmed
+func TestEmailChangeVerification(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+
+	res, body := testutil.Request(t, app, http.MethodPut, "/user/update-user", user.UserUpdateRequest{Email: "first@tester.com"}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	first := testutil.LinkParam(t, testutil.LastMail(t, "first@tester.com"), "token")
+
+	// Changing Again Supersedes The First Link
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/update-user", user.UserUpdateRequest{Email: "second@tester.com"}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	second := testutil.LinkParam(t, testutil.LastMail(t, "second@tester.com"), "token")
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/email/verify", user.VerifyEmailRequest{Token: first}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, token)
+	current := new(UserResponse)
+	testutil.Decode(t, body, current)
+	if current.User.Email != "tester@tester.com" || current.User.PendingEmail != "second@tester.com" {
+		t.Fatalf("\nUnexpected Emails: %s Pending: %s\n", current.User.Email, current.User.PendingEmail)
+	}
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/email/verify", user.VerifyEmailRequest{Token: second}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, token)
+	testutil.Decode(t, body, current)
+	if current.User.Email != "second@tester.com" || current.User.PendingEmail != "" || current.User.EmailVerifiedAt == nil {
+		t.Fatalf("\nEmail Not Swapped: %s Pending: %s\n", current.User.Email, current.User.PendingEmail)
+	}
+}
+
+func TestEmailChangeConflict(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	testutil.CreateUser(t, "other", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+
+	res, body := testutil.Request(t, app, http.MethodPut, "/user/update-user", user.UserUpdateRequest{Email: "other@tester.com"}, token)
+	testutil.ExpectStatus(t, res, body, 409)
+}
+
+func TestResendVerification(t *testing.T) {
+	app := testutil.Setup(t)
+	u := testutil.CreateUser(t, "tester", "123", "default")
+
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/email/resend", user.ResendVerificationRequest{Email: u.Email}, "")
+	testutil.ExpectStatus(t, res, body, 202)
+	token := testutil.LinkParam(t, testutil.LastMail(t, u.Email), "token")
+
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/email/verify", user.VerifyEmailRequest{Token: token}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Nothing Left To Verify - Same Answer, No Email
+	sent := len(testutil.DeliverMail(t).Messages())
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/email/resend", user.ResendVerificationRequest{Email: u.Email}, "")
+	testutil.ExpectStatus(t, res, body, 202)
+	if len(testutil.DeliverMail(t).Messages()) != sent {
+		t.Fatalf("\nSent A Link For A Verified Address\n")
+	}
+}
+
+// Verification Tokens Aren't Access Tokens And Vice Versa
+func TestVerificationTokenAudience(t *testing.T) {
+	app := testutil.Setup(t)
+	u := testutil.CreateUser(t, "tester", "123", "default")
+
+	claims, _ := auth.NewClaims(u.ID, auth.AudienceEmailVerification, time.Hour)
+	claims.Email = u.Email
+	keys, _ := auth.Keys()
+	verification, _ := keys.Sign(claims)
+
+	res, body := testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer "+verification)
+	testutil.ExpectStatus(t, res, body, 401)
+
+	access := testutil.Login(t, app, "tester", "123")
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/email/verify", user.VerifyEmailRequest{Token: access[len("Bearer "):]}, "")
+	testutil.ExpectStatus(t, res, body, 400)
+}
+
+// An Address Set By An Admin Is Unverified Until Its Owner Confirms It
+func TestAdminEmailChangeVerification(t *testing.T) {
+	cfg := testutil.Config(t)
+	cfg.Auth.RequireVerifiedEmail = true
+	app := testutil.SetupWith(t, cfg)
+	manager := testutil.CreateUser(t, "admin", "123", "admin")
+	target := testutil.CreateUser(t, "tester", "123", "default")
+	database.DB.Model(&user.User{}).Where("id IN ?", []uint{manager.ID, target.ID}).Update("email_verified_at", time.Now())
+	admin := testutil.Login(t, app, "admin", "123")
+
+	// Leaving email Out Keeps The Address
+	req := user.AdminUserUpdateRequest{UserID: target.ID, RoleID: target.RoleID}
+	res, body := testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, admin)
+	testutil.ExpectStatus(t, res, body, 200)
+	updated := new(UserResponse)
+	testutil.Decode(t, body, updated)
+	if updated.User.Email != target.Email || updated.User.EmailVerifiedAt == nil {
+		t.Fatalf("\nOmitted Email Changed The Address: %+v\n", updated.User)
+	}
+
+	req.Email = "changed@tester.com"
+	res, body = testutil.Request(t, app, http.MethodPut, "/user/admin-user-update", req, admin)
+	testutil.ExpectStatus(t, res, body, 200)
+	testutil.Decode(t, body, updated)
+	if updated.User.Email != req.Email || updated.User.EmailVerifiedAt != nil || updated.User.PendingEmail != "" {
+		t.Fatalf("\nNew Address Kept The Old Verification: %+v\n", updated.User)
+	}
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/login", user.LoginRequest{Username: "tester", Password: "123"}, "")
+	testutil.ExpectStatus(t, res, body, 403)
+
+	token := testutil.LinkParam(t, testutil.LastMail(t, req.Email), "token")
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/email/verify", user.VerifyEmailRequest{Token: token}, "")
+	testutil.ExpectStatus(t, res, body, 200)
+	testutil.Login(t, app, "tester", "123")
+}
@@ -0,0 +1,529 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/goccy/go-json"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+
+	"app/audit"
+	"app/config"
+	"app/database"
+	"app/logging"
+	"app/util"
+)
+
+// A Passkey - Users Can Register Several, Each With Its Own Name
+type WebAuthnCredential struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UserID          uint       `json:"user_id" gorm:"not null;index"`
+	Name            string     `json:"name" gorm:"type:VARCHAR(64);not null"`
+	CredentialID    string     `json:"credential_id" gorm:"type:VARCHAR(255);not null;uniqueIndex"` // base64url
+	PublicKey       []byte     `json:"-" gorm:"not null"`                                           // COSE Key
+	AttestationType string     `json:"-" gorm:"type:VARCHAR(32)"`
+	Transports      string     `json:"transports" gorm:"type:VARCHAR(128)"` // Comma Separated
+	AAGUID          []byte     `json:"-"`
+	SignCount       uint32     `json:"-" gorm:"not null;default:0"`
+	BackupEligible  bool       `json:"backup_eligible" gorm:"not null;default:false"`
+	BackupState     bool       `json:"backed_up" gorm:"not null;default:false"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+}
+
+/*
+Server Side State Of A Ceremony In Progress - Found Again By The Challenge
+The Authenticator Signed, Deleted When Used So Each Challenge Works Once
+*/
+type WebAuthnSession struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	Challenge string    `gorm:"type:VARCHAR(128);not null;uniqueIndex"`
+	Ceremony  string    `gorm:"type:VARCHAR(16);not null"`
+	UserID    uint      // 0 For Login - The Passkey Says Who It Is
+	Name      string    `gorm:"type:VARCHAR(64)"` // For The Credential Being Registered
+	Data      string    `gorm:"type:TEXT;not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+const (
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+)
+
+var errWebAuthnSession = errors.New("Unknown Or Expired WebAuthn Challenge")
+
+/*
+	Registration
+*/
+
+type BeginWebAuthnRegistrationRequest struct {
+	Name string `json:"name" validate:"omitempty,max=64"`
+}
+
+// Returns The Options For navigator.credentials.create()
+func BeginWebAuthnRegistration(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		logging.For(c).Error("Begin WebAuthn Registration Error: Failed Parsing Uint", "error", err)
+		return c.SendStatus(500)
+	}
+
+	// Body Is Optional
+	r := new(BeginWebAuthnRegistrationRequest)
+	if len(c.Body()) > 0 {
+		err = c.BodyParser(r)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+		}
+	}
+	r.Name = strings.TrimSpace(r.Name)
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+	if r.Name == "" {
+		r.Name = "Passkey"
+	}
+
+	rp, err := relyingParty()
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	user, err := loadWebAuthnUser(c.UserContext(), uint(user_id))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "User Doesn't Exist"})
+	}
+
+	// Authenticators Refuse To Register Twice
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.credentials))
+	for _, credential := range user.WebAuthnCredentials() {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, session, err := rp.BeginRegistration(user,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		logging.For(c).Error("Begin WebAuthn Registration Error", "error", err)
+		return c.SendStatus(500)
+	}
+
+	err = saveWebAuthnSession(c.UserContext(), CeremonyRegistration, uint(user_id), r.Name, session)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	return c.Status(200).JSON(creation)
+}
+
+// Takes The Result Of navigator.credentials.create() And Stores The Passkey
+func FinishWebAuthnRegistration(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		logging.For(c).Error("Finish WebAuthn Registration Error: Failed Parsing Uint", "error", err)
+		return c.SendStatus(500)
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(c.Body()))
+	if err != nil {
+		logging.For(c).Debug("Finish WebAuthn Registration Error", "error", err)
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Credential"})
+	}
+
+	pending, session, err := takeWebAuthnSession(c.UserContext(), CeremonyRegistration, parsed.Response.CollectedClientData.Challenge)
+	if err != nil || pending.UserID != uint(user_id) {
+		return c.Status(400).JSON(fiber.Map{"error": errWebAuthnSession.Error()})
+	}
+
+	rp, err := relyingParty()
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	user, err := loadWebAuthnUser(c.UserContext(), uint(user_id))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "User Doesn't Exist"})
+	}
+
+	credential, err := rp.CreateCredential(user, session, parsed)
+	if err != nil {
+		logging.For(c).Debug("Finish WebAuthn Registration Error", "error", err)
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Credential"})
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, transport := range credential.Transport {
+		transports[i] = string(transport)
+	}
+	stored := WebAuthnCredential{
+		UserID:          uint(user_id),
+		Name:            pending.Name,
+		CredentialID:    base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+	err = database.For(c).Create(&stored).Error
+	if err != nil {
+		logging.For(c).Debug("Finish WebAuthn Registration Error: Failed To Save", "error", err)
+		return c.Status(409).JSON(fiber.Map{"error": "Passkey Already Registered"})
+	}
+	audit.Record(c, audit.Entry{
+		Type:       audit.PasskeyAdded,
+		TargetType: audit.TargetUser,
+		TargetID:   uint(user_id),
+		Metadata:   fiber.Map{"credential_id": stored.ID, "name": stored.Name},
+	})
+	return c.Status(201).JSON(fiber.Map{"credential": stored})
+}
+
+/*
+	Login
+*/
+
+// Returns The Options For navigator.credentials.get() - Any Of The User's Passkeys Will Do
+func BeginWebAuthnLogin(c *fiber.Ctx) error {
+	rp, err := relyingParty()
+	if err != nil {
+		return c.SendStatus(500)
+	}
+
+	assertion, session, err := rp.BeginDiscoverableLogin()
+	if err != nil {
+		logging.For(c).Error("Begin WebAuthn Login Error", "error", err)
+		return c.SendStatus(500)
+	}
+
+	err = saveWebAuthnSession(c.UserContext(), CeremonyLogin, 0, "", session)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	return c.Status(200).JSON(assertion)
+}
+
+// Takes The Result Of navigator.credentials.get() - Issues Tokens Like Login
+func FinishWebAuthnLogin(c *fiber.Ctx) error {
+	failed := fiber.Map{"error": "Passkey Login Failed"}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(c.Body()))
+	if err != nil {
+		logging.For(c).Debug("Finish WebAuthn Login Error", "error", err)
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Credential"})
+	}
+
+	_, session, err := takeWebAuthnSession(c.UserContext(), CeremonyLogin, parsed.Response.CollectedClientData.Challenge)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": errWebAuthnSession.Error()})
+	}
+
+	rp, err := relyingParty()
+	if err != nil {
+		return c.SendStatus(500)
+	}
+
+	var user *webAuthnUser
+	credential, err := rp.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		user_id, ok := userIDFromHandle(userHandle)
+		if !ok {
+			return nil, errors.New("Invalid User Handle")
+		}
+		user, err = loadWebAuthnUser(c.UserContext(), user_id)
+		return user, err
+	}, session, parsed)
+
+	// Throttled Like Login - A Locked Account Stays Locked With A Passkey Too
+	targets := []string{addressTarget(c.IP())}
+	if user != nil {
+		targets = append(targets, accountTarget(user.ID))
+	}
+	blocked, throttleErr := loginThrottle(c.UserContext(), targets...)
+	if throttleErr != nil {
+		return c.SendStatus(500)
+	}
+	if blocked != nil {
+		return throttled(c, blocked)
+	}
+
+	if err != nil {
+		logging.For(c).Debug("Finish WebAuthn Login Error", "error", err)
+		if user != nil {
+			loginFailed(c, user.Username, user.User, "passkey")
+		} else {
+			loginFailed(c, "", nil, "passkey")
+		}
+		return c.Status(401).JSON(failed)
+	}
+
+	// A Counter Going Backwards Means The Key Was Cloned
+	if credential.Authenticator.CloneWarning {
+		logging.For(c).Warn("WebAuthn Clone Warning", "user_id", user.ID, "credential_id", base64.RawURLEncoding.EncodeToString(credential.ID))
+		loginFailed(c, user.Username, user.User, "passkey_cloned")
+		return c.Status(401).JSON(failed)
+	}
+
+	// Same Checks As Login - The Passkey Replaces Both Password And Second Factor
+	if !*user.AccountEnabled {
+		auditLoginFailed(c, user.Username, user.User, "account_disabled")
+		return c.Status(403).JSON(fiber.Map{"error": "User Account Disabled"})
+	}
+	if config.Get().Auth.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return c.Status(403).JSON(fiber.Map{"error": "Email Not Verified"})
+	}
+
+	now := time.Now()
+	err = database.For(c).Model(&WebAuthnCredential{}).
+		Where("credential_id = ?", base64.RawURLEncoding.EncodeToString(credential.ID)).
+		Updates(map[string]interface{}{
+			"sign_count":   credential.Authenticator.SignCount,
+			"backup_state": credential.Flags.BackupState,
+			"last_used_at": now,
+		}).Error
+	if err != nil {
+		return c.SendStatus(500)
+	}
+
+	token, refreshToken, err := issueTokens(c.UserContext(), user.User)
+	if err != nil {
+		logging.For(c).Error("WebAuthn Login JWT Error", "error", err)
+		return c.SendStatus(500)
+	}
+	clearLoginFailures(c.UserContext(), user.ID)
+	auditLogin(c, user.User, "passkey")
+	user.Password = ""
+	return c.Status(200).JSON(fiber.Map{"token": token, "refresh_token": refreshToken, "user": user.User})
+}
+
+/*
+	Managing Passkeys
+*/
+
+func GetWebAuthnCredentials(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+
+	var credentials []WebAuthnCredential
+	err = database.For(c).Where("user_id = ?", user_id).Order("id").Find(&credentials).Error
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	return c.Status(200).JSON(fiber.Map{"credentials": credentials})
+}
+
+type RenameWebAuthnCredentialRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=64"`
+}
+
+func RenameWebAuthnCredential(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Credential ID"})
+	}
+
+	r := new(RenameWebAuthnCredentialRequest)
+	err = c.BodyParser(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input Fields"})
+	}
+	r.Name = strings.TrimSpace(r.Name)
+	err = util.Validate(r)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Input"})
+	}
+
+	res := database.For(c).Model(&WebAuthnCredential{}).Where("id = ? AND user_id = ?", id, user_id).Update("name", r.Name)
+	if res.Error != nil {
+		return c.SendStatus(500)
+	}
+	if res.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Passkey Doesn't Exist"})
+	}
+	return c.SendStatus(200)
+}
+
+func DeleteWebAuthnCredential(c *fiber.Ctx) error {
+	// Get UserID From Locals
+	user_id, err := strconv.ParseUint(fmt.Sprintf("%s", c.Locals("user_id")), 10, 32)
+	if err != nil {
+		return c.SendStatus(500)
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid Credential ID"})
+	}
+
+	res := database.For(c).Where("id = ? AND user_id = ?", id, user_id).Delete(&WebAuthnCredential{})
+	if res.Error != nil {
+		return c.SendStatus(500)
+	}
+	if res.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "Passkey Doesn't Exist"})
+	}
+	audit.Record(c, audit.Entry{
+		Type:       audit.PasskeyRemoved,
+		TargetType: audit.TargetUser,
+		TargetID:   uint(user_id),
+		Metadata:   fiber.Map{"credential_id": id},
+	})
+	return c.SendStatus(200)
+}
+
+/*
+	Helpers
+*/
+
+// Built From The Current Config - Cheap Enough To Do Per Request
+func relyingParty() (*webauthn.WebAuthn, error) {
+	cfg := config.Get().Auth
+	return webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthnRPID,
+		RPDisplayName: cfg.WebAuthnRPName,
+		RPOrigins:     cfg.WebAuthnOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true},
+			Registration: webauthn.TimeoutConfig{Enforce: true},
+		},
+	})
+}
+
+// A User With Their Passkeys - Satisfies webauthn.User
+type webAuthnUser struct {
+	*User
+	credentials []WebAuthnCredential
+}
+
+func loadWebAuthnUser(ctx context.Context, userID uint) (*webAuthnUser, error) {
+	db := database.DB.WithContext(ctx)
+	user := new(User)
+	err := db.Preload("Role").First(user, userID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials []WebAuthnCredential
+	err = db.Where("user_id = ?", userID).Find(&credentials).Error
+	if err != nil {
+		return nil, err
+	}
+	return &webAuthnUser{User: user, credentials: credentials}, nil
+}
+
+// The User Handle - An Opaque ID Rather Than Anything Personal
+func (u *webAuthnUser) WebAuthnID() []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(u.ID))
+	return handle
+}
+
+func userIDFromHandle(handle []byte) (uint, bool) {
+	if len(handle) != 8 {
+		return 0, false
+	}
+	return uint(binary.BigEndian.Uint64(handle)), true
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.Username
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.Username
+}
+
+// Deprecated In The Spec But Still Part Of webauthn.User
+func (u *webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, stored := range u.credentials {
+		id, err := base64.RawURLEncoding.DecodeString(stored.CredentialID)
+		if err != nil {
+			continue
+		}
+		var transports []protocol.AuthenticatorTransport
+		for _, transport := range strings.Split(stored.Transports, ",") {
+			if transport != "" {
+				transports = append(transports, protocol.AuthenticatorTransport(transport))
+			}
+		}
+		credentials = append(credentials, webauthn.Credential{
+			ID:              id,
+			PublicKey:       stored.PublicKey,
+			AttestationType: stored.AttestationType,
+			Transport:       transports,
+			Flags:           webauthn.CredentialFlags{BackupEligible: stored.BackupEligible, BackupState: stored.BackupState},
+			Authenticator:   webauthn.Authenticator{AAGUID: stored.AAGUID, SignCount: stored.SignCount},
+		})
+	}
+	return credentials
+}
+
+func saveWebAuthnSession(ctx context.Context, ceremony string, userID uint, name string, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	db := database.DB.WithContext(ctx)
+
+	// Abandoned Ceremonies Are Cleared As New Ones Start
+	db.Where("expires_at < ?", time.Now()).Delete(&WebAuthnSession{})
+
+	return db.Create(&WebAuthnSession{
+		Challenge: session.Challenge,
+		Ceremony:  ceremony,
+		UserID:    userID,
+		Name:      name,
+		Data:      string(data),
+		ExpiresAt: session.Expires,
+	}).Error
+}
+
+// Looks Up And Deletes The Session For A Challenge - Only One Caller Gets It
+func takeWebAuthnSession(ctx context.Context, ceremony string, challenge string) (*WebAuthnSession, webauthn.SessionData, error) {
+	var session webauthn.SessionData
+	var pending WebAuthnSession
+
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("challenge = ? AND ceremony = ? AND expires_at > ?", challenge, ceremony, time.Now()).First(&pending).Error
+		if err != nil {
+			return err
+		}
+		res := tx.Where("id = ?", pending.ID).Delete(&WebAuthnSession{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errWebAuthnSession
+		}
+		return json.Unmarshal([]byte(pending.Data), &session)
+	})
+	if err != nil {
+		return nil, session, errWebAuthnSession
+	}
+	return &pending, session, nil
+}
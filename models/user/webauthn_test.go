@@ -0,0 +1,163 @@
+package user_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/config"
+	"app/database"
+	"app/models/user"
+	"app/testutil"
+)
+
+type CredentialsResponse struct {
+	Credentials []user.WebAuthnCredential `json:"credentials"`
+}
+
+// Registers The Authenticator's Passkey For The Logged In User
+func registerPasskey(t *testing.T, app *fiber.App, token string, authenticator *testutil.Authenticator, name string) (*http.Response, []byte) {
+	t.Helper()
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/webauthn/register/begin", user.BeginWebAuthnRegistrationRequest{Name: name}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	return testutil.Request(t, app, http.MethodPost, "/user/webauthn/register/finish", authenticator.Create(t, body), token)
+}
+
+func passkeyLogin(t *testing.T, app *fiber.App, authenticator *testutil.Authenticator) (fiber.Map, *http.Response, []byte) {
+	t.Helper()
+	res, body := testutil.Request(t, app, http.MethodPost, "/user/webauthn/login/begin", nil, "")
+	testutil.ExpectStatus(t, res, body, 200)
+	assertion := authenticator.Get(t, body, config.Get().Auth.WebAuthnRPID)
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/webauthn/login/finish", assertion, "")
+	return assertion, res, body
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+	authenticator := testutil.NewAuthenticator(t, config.Get().Auth.WebAuthnOrigins[0])
+
+	res, body := registerPasskey(t, app, token, authenticator, "Laptop")
+	testutil.ExpectStatus(t, res, body, 201)
+
+	assertion, res, body := passkeyLogin(t, app, authenticator)
+	testutil.ExpectStatus(t, res, body, 200)
+	tokens := new(TokenResponse)
+	testutil.Decode(t, body, tokens)
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer "+tokens.Token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Challenges Are Single Use
+	res, body = testutil.Request(t, app, http.MethodPost, "/user/webauthn/login/finish", assertion, "")
+	testutil.ExpectStatus(t, res, body, 400)
+
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/webauthn/credentials", nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	credentials := new(CredentialsResponse)
+	testutil.Decode(t, body, credentials)
+	if len(credentials.Credentials) != 1 || credentials.Credentials[0].Name != "Laptop" || credentials.Credentials[0].LastUsedAt == nil {
+		t.Fatalf("\nUnexpected Credentials: %s\n", body)
+	}
+}
+
+func TestMultiplePasskeys(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+	origin := config.Get().Auth.WebAuthnOrigins[0]
+	laptop := testutil.NewAuthenticator(t, origin)
+	phone := testutil.NewAuthenticator(t, origin)
+
+	res, body := registerPasskey(t, app, token, laptop, "Laptop")
+	testutil.ExpectStatus(t, res, body, 201)
+	res, body = registerPasskey(t, app, token, phone, "")
+	testutil.ExpectStatus(t, res, body, 201)
+
+	// The Same Passkey Can't Be Registered Twice
+	res, body = registerPasskey(t, app, token, laptop, "Laptop Again")
+	testutil.ExpectStatus(t, res, body, 409)
+
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/webauthn/credentials", nil, token)
+	credentials := new(CredentialsResponse)
+	testutil.Decode(t, body, credentials)
+	if len(credentials.Credentials) != 2 || credentials.Credentials[1].Name != "Passkey" {
+		t.Fatalf("\nUnexpected Credentials: %s\n", body)
+	}
+	phoneID := credentials.Credentials[1].ID
+
+	res, body = testutil.Request(t, app, http.MethodPut, fmt.Sprintf("/user/webauthn/credentials/%d", phoneID), user.RenameWebAuthnCredentialRequest{Name: "Phone"}, token)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Removing One Leaves The Other Working
+	res, body = testutil.Request(t, app, http.MethodDelete, fmt.Sprintf("/user/webauthn/credentials/%d", phoneID), nil, token)
+	testutil.ExpectStatus(t, res, body, 200)
+	_, res, body = passkeyLogin(t, app, phone)
+	testutil.ExpectStatus(t, res, body, 401)
+	_, res, body = passkeyLogin(t, app, laptop)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Other Users Can't Touch Them
+	testutil.CreateUser(t, "other", "123", "default")
+	other := testutil.Login(t, app, "other", "123")
+	res, body = testutil.Request(t, app, http.MethodDelete, fmt.Sprintf("/user/webauthn/credentials/%d", credentials.Credentials[0].ID), nil, other)
+	testutil.ExpectStatus(t, res, body, 404)
+}
+
+func TestPasskeyRejected(t *testing.T) {
+	app := testutil.Setup(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+
+	// Phishing Site
+	phished := testutil.NewAuthenticator(t, "https://evil.example.com")
+	res, body := registerPasskey(t, app, token, phished, "")
+	testutil.ExpectStatus(t, res, body, 400)
+
+	authenticator := testutil.NewAuthenticator(t, config.Get().Auth.WebAuthnOrigins[0])
+	res, body = registerPasskey(t, app, token, authenticator, "")
+	testutil.ExpectStatus(t, res, body, 201)
+	_, res, body = passkeyLogin(t, app, authenticator)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// A Sign Count Going Backwards Looks Like A Cloned Key
+	authenticator.SignCount = 0
+	_, res, body = passkeyLogin(t, app, authenticator)
+	testutil.ExpectStatus(t, res, body, 401)
+}
+
+// Passkeys Go Through The Same Throttle As Passwords
+func TestPasskeyLockout(t *testing.T) {
+	app := lockoutApp(t)
+	testutil.CreateUser(t, "tester", "123", "default")
+	token := testutil.Login(t, app, "tester", "123")
+	authenticator := testutil.NewAuthenticator(t, config.Get().Auth.WebAuthnOrigins[0])
+	res, body := registerPasskey(t, app, token, authenticator, "")
+	testutil.ExpectStatus(t, res, body, 201)
+	_, res, body = passkeyLogin(t, app, authenticator)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	// Rejected Assertions Count Towards The Lockout
+	authenticator.SignCount = 0
+	_, res, body = passkeyLogin(t, app, authenticator)
+	testutil.ExpectStatus(t, res, body, 401)
+	var count int64
+	database.DB.Model(&user.LoginThrottle{}).Where("target LIKE ? AND failures = 1", "user:%").Count(&count)
+	if count != 1 {
+		t.Fatalf("\nFailed Passkey Login Wasn't Counted\n")
+	}
+	authenticator.SignCount = 100
+
+	// Locked By Password Guessing - The Passkey Doesn't Get Around It
+	wrong := user.LoginRequest{Username: "tester", Password: "wrong"}
+	for i := 0; i < 3; i++ {
+		skipDelay(t)
+		res, body = testutil.Request(t, app, http.MethodPost, "/user/login", wrong, "")
+		testutil.ExpectStatus(t, res, body, 400)
+	}
+	skipDelay(t)
+	_, res, body = passkeyLogin(t, app, authenticator)
+	testutil.ExpectStatus(t, res, body, 423)
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Counters For One Instance - Lost On Restart
+type MemoryStore struct {
+	mu      sync.Mutex
+	windows map[string]memoryWindow
+	swept   time.Time
+}
+
+type memoryWindow struct {
+	count int
+	reset time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{windows: map[string]memoryWindow{}}
+}
+
+func (m *MemoryStore) Hit(ctx context.Context, key string, window time.Duration, now time.Time) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Drop Finished Windows Now And Then So Idle Keys Don't Pile Up
+	if now.Sub(m.swept) > time.Minute {
+		for k, w := range m.windows {
+			if !now.Before(w.reset) {
+				delete(m.windows, k)
+			}
+		}
+		m.swept = now
+	}
+
+	w, ok := m.windows[key]
+	if !ok || !now.Before(w.reset) {
+		w = memoryWindow{reset: now.Add(window)}
+	}
+	w.count++
+	m.windows[key] = w
+	return w.count, w.reset, nil
+}
@@ -0,0 +1,111 @@
+/*
+Fixed Window Rate Limits - Routes Name A Policy From rate_limit.policies
+And Every Response Carries RateLimit-* Headers, 429s Also Retry-After
+*/
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/config"
+	"app/logging"
+)
+
+/*
+Counts Hits Per Key - Hit Adds One And Returns The Count So Far In The
+Current Window Along With When That Window Ends
+*/
+type Store interface {
+	Hit(ctx context.Context, key string, window time.Duration, now time.Time) (int, time.Time, error)
+}
+
+var store atomic.Pointer[Store]
+
+// Builds The Store For The Configured Backend And Makes It Current
+func Configure(cfg *config.Config) error {
+	var s Store
+	switch cfg.RateLimit.Store {
+	case config.RateLimitMemory:
+		s = NewMemoryStore()
+	case config.RateLimitSQL:
+		s = SQLStore{}
+	default:
+		return fmt.Errorf("Unknown Rate Limit Store: %q", cfg.RateLimit.Store)
+	}
+	SetStore(s)
+	return nil
+}
+
+func SetStore(s Store) {
+	store.Store(&s)
+}
+
+// Current Store - A Fresh Memory Store Until Configure Or SetStore Runs
+func Current() Store {
+	s := store.Load()
+	if s == nil {
+		SetStore(NewMemoryStore())
+		s = store.Load()
+	}
+	return *s
+}
+
+/*
+Limits Requests Under The Named Policy - Unknown Policies Aren't Limited
+Keyed By user Or token It Has To Run After auth.Authenticate
+*/
+func Policy(name string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cfg := config.Get().RateLimit
+		policy, ok := cfg.Policies[name]
+		if !cfg.Enabled || !ok {
+			return c.Next()
+		}
+
+		now := time.Now()
+		window := time.Duration(policy.Window) * time.Second
+		count, reset, err := Current().Hit(c.UserContext(), name+":"+Key(c, policy.Key), window, now)
+		if err != nil {
+			// Better To Let Traffic Through Than To Fail Every Request
+			logging.For(c).Error("Rate Limit Error", "error", err)
+			return c.Next()
+		}
+
+		remaining := policy.Limit - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		seconds := strconv.FormatInt(int64(reset.Sub(now).Round(time.Second)/time.Second), 10)
+		c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, policy.Window))
+		c.Set("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Set("RateLimit-Reset", seconds)
+
+		if count > policy.Limit {
+			c.Set(fiber.HeaderRetryAfter, seconds)
+			return c.Status(429).JSON(fiber.Map{"error": "Too Many Requests"})
+		}
+		return c.Next()
+	}
+}
+
+// Who A Request Counts Against For The Given Key Kind - Addresses Honour server.trusted_proxies
+func Key(c *fiber.Ctx, kind string) string {
+	if kind == config.RateLimitByToken {
+		if id, ok := c.Locals("token_id").(uint); ok {
+			return fmt.Sprintf("token:%d", id)
+		}
+	}
+	if kind == config.RateLimitByToken || kind == config.RateLimitByUser {
+		if id, ok := c.Locals("user_id").(string); ok && id != "" {
+			return "user:" + id
+		}
+	}
+	return "ip:" + c.IP()
+}
@@ -0,0 +1,152 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"app/config"
+	"app/models/user"
+	"app/ratelimit"
+	"app/testutil"
+)
+
+func testStore(t *testing.T, store ratelimit.Store) {
+	now := time.Now()
+	for i := 1; i <= 3; i++ {
+		count, reset, err := store.Hit(context.Background(), "a", time.Minute, now)
+		if err != nil {
+			t.Fatalf("\nHit Failed: %s\n", err.Error())
+		}
+		if count != i || !reset.Equal(now.Add(time.Minute)) {
+			t.Fatalf("\nHit %d: Count %d Reset %s\n", i, count, reset)
+		}
+	}
+
+	// Keys Are Counted Apart
+	count, _, _ := store.Hit(context.Background(), "b", time.Minute, now)
+	if count != 1 {
+		t.Fatalf("\nUnexpected Count For b: %d Expected: 1\n", count)
+	}
+
+	// A New Window Starts Again
+	later := now.Add(time.Minute)
+	count, reset, _ := store.Hit(context.Background(), "a", time.Minute, later)
+	if count != 1 || !reset.Equal(later.Add(time.Minute)) {
+		t.Fatalf("\nWindow Didn't Reset: Count %d Reset %s\n", count, reset)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, ratelimit.NewMemoryStore())
+}
+
+func TestSQLStore(t *testing.T) {
+	testutil.Setup(t)
+	testStore(t, ratelimit.SQLStore{})
+}
+
+func limitedApp(t *testing.T, store string) *fiber.App {
+	cfg := testutil.Config(t)
+	cfg.RateLimit.Enabled = true
+	cfg.RateLimit.Store = store
+	cfg.RateLimit.Policies = map[string]config.RateLimitPolicy{
+		"login":         {Limit: 2, Window: 60, Key: config.RateLimitByIP},
+		"authenticated": {Limit: 2, Window: 60, Key: config.RateLimitByToken},
+	}
+	return testutil.SetupWith(t, cfg)
+}
+
+func TestPolicy(t *testing.T) {
+	for _, store := range []string{config.RateLimitMemory, config.RateLimitSQL} {
+		t.Run(store, func(t *testing.T) {
+			app := limitedApp(t, store)
+			testutil.CreateUser(t, "tester", "123", "default")
+			login := user.LoginRequest{Username: "tester", Password: "123"}
+
+			for i, remaining := range []string{"1", "0"} {
+				res, body := testutil.Request(t, app, http.MethodPost, "/user/login", login, "")
+				testutil.ExpectStatus(t, res, body, 200)
+				if res.Header.Get("RateLimit-Limit") != "2" || res.Header.Get("RateLimit-Remaining") != remaining {
+					t.Fatalf("\nRequest %d: Limit %q Remaining %q\n", i, res.Header.Get("RateLimit-Limit"), res.Header.Get("RateLimit-Remaining"))
+				}
+			}
+
+			res, body := testutil.Request(t, app, http.MethodPost, "/user/login", login, "")
+			testutil.ExpectStatus(t, res, body, 429)
+			if res.Header.Get("Retry-After") == "" || res.Header.Get("RateLimit-Reset") == "" {
+				t.Fatalf("\nMissing Retry-After Or RateLimit-Reset: %v\n", res.Header)
+			}
+
+			// Routes Without A Policy Aren't Limited
+			res, body = testutil.Request(t, app, http.MethodPost, "/user/token/refresh", fiber.Map{"refresh_token": "x"}, "")
+			testutil.ExpectStatus(t, res, body, 401)
+		})
+	}
+}
+
+// Authenticated Limits Follow The Caller, Not The Address
+func TestPolicyPerUser(t *testing.T) {
+	app := limitedApp(t, config.RateLimitMemory)
+	testutil.CreateUser(t, "one", "123", "default")
+	testutil.CreateUser(t, "two", "123", "default")
+	one := testutil.Login(t, app, "one", "123")
+	two := testutil.Login(t, app, "two", "123")
+
+	for i := 0; i < 2; i++ {
+		res, body := testutil.Request(t, app, http.MethodGet, "/user/", nil, one)
+		testutil.ExpectStatus(t, res, body, 200)
+	}
+	res, body := testutil.Request(t, app, http.MethodGet, "/user/", nil, one)
+	testutil.ExpectStatus(t, res, body, 429)
+
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, two)
+	testutil.ExpectStatus(t, res, body, 200)
+}
+
+// Behind A Trusted Proxy Each Client Gets Its Own Bucket - Anyone Else's Header Is Ignored
+func TestPolicyBehindProxy(t *testing.T) {
+	send := func(t *testing.T, app *fiber.App, client string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/user/login", strings.NewReader(`{"username": "tester", "password": "123"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(fiber.HeaderXForwardedFor, client)
+		res, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("\nLogin Failed: %s\n", err.Error())
+		}
+		return res.StatusCode
+	}
+
+	for name, trusted := range map[string]string{"trusted": "0.0.0.0/0", "untrusted": "10.1.2.3"} {
+		t.Run(name, func(t *testing.T) {
+			cfg := testutil.Config(t)
+			cfg.RateLimit.Enabled = true
+			cfg.RateLimit.Policies = map[string]config.RateLimitPolicy{
+				"login": {Limit: 2, Window: 60, Key: config.RateLimitByIP},
+			}
+			cfg.Server.ProxyHeader = fiber.HeaderXForwardedFor
+			cfg.Server.TrustedProxies = []string{trusted}
+			app := testutil.SetupWith(t, cfg)
+			testutil.CreateUser(t, "tester", "123", "default")
+
+			for i := 0; i < 2; i++ {
+				if status := send(t, app, "203.0.113.1"); status != 200 {
+					t.Fatalf("\nRequest %d: %d Expected: 200\n", i, status)
+				}
+			}
+			expected := 429
+			if name == "trusted" {
+				expected = 200
+			}
+			if status := send(t, app, "203.0.113.2"); status != expected {
+				t.Fatalf("\nSecond Client: %d Expected: %d\n", status, expected)
+			}
+		})
+	}
+}
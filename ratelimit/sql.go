@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"app/database"
+	"app/logging"
+)
+
+// One Row Per Bucket - Shared By Every Instance On The Database
+type RateLimitCounter struct {
+	Bucket  string    `gorm:"type:VARCHAR(191);primaryKey"`
+	Count   int       `gorm:"not null;default:0"`
+	ResetAt time.Time `gorm:"not null;index"`
+}
+
+// Counters In database.DB - Each Hit Is One Upsert And One Read
+type SQLStore struct{}
+
+// Unix Time Of This Instance's Last Sweep
+var lastSweep atomic.Int64
+
+func (SQLStore) Hit(ctx context.Context, key string, window time.Duration, now time.Time) (int, time.Time, error) {
+	db := database.DB.WithContext(ctx)
+	reset := now.Add(window)
+
+	// A Finished Window Starts Again From One
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "bucket"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"count":    gorm.Expr("CASE WHEN rate_limit_counters.reset_at <= ? THEN 1 ELSE rate_limit_counters.count + 1 END", now),
+			"reset_at": gorm.Expr("CASE WHEN rate_limit_counters.reset_at <= ? THEN ? ELSE rate_limit_counters.reset_at END", now, reset),
+		}),
+	}).Create(&RateLimitCounter{Bucket: key, Count: 1, ResetAt: reset}).Error
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	var counter RateLimitCounter
+	err = db.Where("bucket = ?", key).First(&counter).Error
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	// Finished Windows Are Cleared Out At Most Once A Minute
+	last := lastSweep.Load()
+	if now.Unix()-last > 60 && lastSweep.CompareAndSwap(last, now.Unix()) {
+		err = db.Where("reset_at <= ?", now).Delete(&RateLimitCounter{}).Error
+		if err != nil {
+			logging.Get().Error("Rate Limit Sweep Error", "error", err)
+		}
+	}
+	return counter.Count, counter.ResetAt, nil
+}
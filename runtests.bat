@@ -1,3 +1,3 @@
 go clean -testcache
 
-go test app/models/user
\ No newline at end of file
+go test ./...
\ No newline at end of file
@@ -2,8 +2,18 @@ package server
 
 import (
 	"app/api"
+	"app/api/auth"
+	"app/audit"
 	"app/database"
+	"app/database/migrations"
 	"app/database/seed"
+	"app/logging"
+	"app/mail"
+	"app/metrics"
+	"app/models/user"
+	"app/ratelimit"
+	"app/tracing"
+	"context"
 	"fmt"
 	"log"
 
@@ -15,29 +25,129 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
-func Start() {
+func Start(cfg *config.Config) {
+	// Refuse To Boot PROD With Insecure Settings
+	err := cfg.SafetyCheck()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	app := Setup(cfg)
+
+	// Deliver Queued Mail In The Background
+	go mail.RunWorker(context.Background())
+	// Clear Out Expired Role Grants
+	go user.RunRoleGrantSweeper(context.Background())
+	// Sign The Head Of The Audit Chain Now And Then
+	go audit.RunCheckpointer(context.Background())
+
+	APP_PORT := ":" + fmt.Sprintf("%d", cfg.App.Port)
+	// Start API
+	logging.Get().Info("Starting App", "url", "http://localhost"+APP_PORT)
+	err = app.Listen(APP_PORT)
+	// Don't Lose Spans Still Waiting For A Batch
+	tracing.Shutdown(context.Background())
+	log.Fatal(err)
+}
+
+// Connects, Migrates And Seeds The Database Then Builds The App - Everything But Listen
+func Setup(cfg *config.Config) *fiber.App {
+	// Make Config Available To Handlers
+	config.Set(cfg)
+	// Everything After This Logs Through It
+	logging.Configure(cfg)
+	// Pick Where Spans Go
+	err := tracing.Configure(cfg)
+	if err != nil {
+		log.Fatalf("Unable To Configure Tracing: %v", err)
+	}
+	// Load Token Signing Keys Or Die
+	err = auth.LoadKeys(cfg.Auth)
+	if err != nil {
+		log.Fatalf("Unable To Load Signing Keys: %v", err)
+	}
+	// Pick The Mail Transport
+	err = mail.Configure(cfg)
+	if err != nil {
+		log.Fatalf("Unable To Configure Mail: %v", err)
+	}
+	// Pick Where Rate Limit Counters Live
+	err = ratelimit.Configure(cfg)
+	if err != nil {
+		log.Fatalf("Unable To Configure Rate Limits: %v", err)
+	}
 	// Initalize Database Or Die
-	database.InitDB()
+	database.InitDB(cfg)
+	// Time Every Statement And Report The Pool
+	err = metrics.InstrumentDB(database.DB)
+	if err != nil {
+		log.Fatalf("Unable To Instrument Database: %v", err)
+	}
+	// Span Statements Run With A Request's Context
+	err = tracing.InstrumentDB(database.DB)
+	if err != nil {
+		log.Fatalf("Unable To Instrument Database: %v", err)
+	}
+	// Bring The Schema Up To Date
+	Migrate(cfg)
 	// Seed Database
 	seed.Seed()
+	return NewApp(cfg)
+}
+
+func NewApp(cfg *config.Config) *fiber.App {
 	// Create New App With Faster JSON Encoder
 	app := fiber.New(fiber.Config{
 		JSONEncoder: json.Marshal,
 		JSONDecoder: json.Unmarshal,
+		// Startup Is Logged Through logging Instead
+		DisableStartupMessage: true,
+		// c.IP Reads ProxyHeader Only From TrustedProxies, Falling Back To The Peer
+		ProxyHeader:             cfg.Server.ProxyHeader,
+		EnableTrustedProxyCheck: cfg.Server.ProxyHeader != "",
+		TrustedProxies:          cfg.Server.TrustedProxies,
+		EnableIPValidation:      true,
 	})
 
-	// Set Routes & Middleware
+	// Set Routes & Middleware - Outermost First So Panics Still Get Logged
+	app.Use(logging.RequestID())
+	app.Use(tracing.Middleware())
+	app.Use(metrics.HTTP())
+	app.Use(logging.AccessLog())
+	app.Use(logging.Errors())
 	app.Use(recover.New())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: config.ALLOW_ORIGINS,
-		AllowHeaders: config.ALLOW_HEADERS,
+		AllowOrigins: cfg.CORS.AllowOrigins,
+		AllowHeaders: cfg.CORS.AllowHeaders,
 	}))
 
 	// Configure API Routes
 	api.SetupAPI(app)
+	// Prometheus Scrapes
+	if cfg.Metrics.Enabled {
+		app.Get(cfg.Metrics.Path, metrics.Handler(cfg.Metrics))
+	}
+	return app
+}
 
-	APP_PORT := ":" + fmt.Sprintf("%d", config.APP_PORT)
-	// Start API
-	fmt.Printf("\nStarting app at http://localhost%s\n", APP_PORT)
-	log.Fatal(app.Listen(APP_PORT))
+// Applies Pending Migrations, Or Refuses To Start If They're Disabled And Any Are Pending
+func Migrate(cfg *config.Config) {
+	if !cfg.DB.MigrateOnStart {
+		pending, err := migrations.Pending(database.DB)
+		if err != nil {
+			log.Fatalf("Unable To Read Migration Status: %v", err)
+		}
+		if pending > 0 {
+			log.Fatalf("%d Pending Migration(s) - Run: migrate up", pending)
+		}
+		return
+	}
+
+	ran, err := migrations.Up(database.DB)
+	if err != nil {
+		log.Fatalf("Unable To Migrate Database: %v", err)
+	}
+	if len(ran) > 0 {
+		logging.Get().Info("Applied Migrations", "count", len(ran))
+	}
 }
@@ -0,0 +1,150 @@
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/goccy/go-json"
+	"github.com/gofiber/fiber/v2"
+)
+
+/*
+Software WebAuthn Authenticator Holding A Single ES256 Passkey - Answers
+The Options From The begin Endpoints With What A Browser Would Send
+To finish, Using "none" Attestation
+*/
+type Authenticator struct {
+	Origin       string
+	CredentialID []byte
+	UserHandle   []byte
+	SignCount    uint32
+	key          *ecdsa.PrivateKey
+}
+
+func NewAuthenticator(t *testing.T, origin string) *Authenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("\nFailed To Generate Passkey: %s\n", err.Error())
+	}
+	id := make([]byte, 32)
+	rand.Read(id)
+	return &Authenticator{Origin: origin, CredentialID: id, key: key}
+}
+
+var b64 = base64.RawURLEncoding
+
+// Answers register/begin - Returns The Body For register/finish
+func (a *Authenticator) Create(t *testing.T, options []byte) fiber.Map {
+	t.Helper()
+	var creation protocol.CredentialCreation
+	Decode(t, options, &creation)
+
+	handle, _ := creation.Response.User.ID.(string)
+	userHandle, err := b64.DecodeString(handle)
+	if err != nil {
+		t.Fatalf("\nInvalid User Handle: %s\n", err.Error())
+	}
+	a.UserHandle = userHandle
+
+	clientData := a.clientData(t, protocol.CreateCeremony, creation.Response.Challenge)
+
+	// COSE EC2 Key - kty: EC2, alg: ES256, crv: P-256
+	size := 32
+	publicKey, err := webauthncbor.Marshal(map[int]interface{}{
+		1:  2,
+		3:  -7,
+		-1: 1,
+		-2: a.key.X.FillBytes(make([]byte, size)),
+		-3: a.key.Y.FillBytes(make([]byte, size)),
+	})
+	if err != nil {
+		t.Fatalf("\nFailed To Encode Public Key: %s\n", err.Error())
+	}
+
+	// Attested Credential Data: AAGUID, Credential ID Length, Credential ID, Public Key
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.CredentialID)))
+	attested = append(attested, a.CredentialID...)
+	attested = append(attested, publicKey...)
+
+	authData := a.authData(creation.Response.RelyingParty.ID, 0x45) // UP | UV | AT
+	authData = append(authData, attested...)
+
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		t.Fatalf("\nFailed To Encode Attestation: %s\n", err.Error())
+	}
+
+	return fiber.Map{
+		"id":    b64.EncodeToString(a.CredentialID),
+		"rawId": b64.EncodeToString(a.CredentialID),
+		"type":  "public-key",
+		"response": fiber.Map{
+			"clientDataJSON":    b64.EncodeToString(clientData),
+			"attestationObject": b64.EncodeToString(attestation),
+			"transports":        []string{"internal"},
+		},
+	}
+}
+
+// Answers login/begin - Returns The Body For login/finish
+func (a *Authenticator) Get(t *testing.T, options []byte, rpID string) fiber.Map {
+	t.Helper()
+	var assertion protocol.CredentialAssertion
+	Decode(t, options, &assertion)
+
+	clientData := a.clientData(t, protocol.AssertCeremony, assertion.Response.Challenge)
+	a.SignCount++
+	authData := a.authData(rpID, 0x05) // UP | UV
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatalf("\nFailed To Sign Assertion: %s\n", err.Error())
+	}
+
+	return fiber.Map{
+		"id":    b64.EncodeToString(a.CredentialID),
+		"rawId": b64.EncodeToString(a.CredentialID),
+		"type":  "public-key",
+		"response": fiber.Map{
+			"clientDataJSON":    b64.EncodeToString(clientData),
+			"authenticatorData": b64.EncodeToString(authData),
+			"signature":         b64.EncodeToString(signature),
+			"userHandle":        b64.EncodeToString(a.UserHandle),
+		},
+	}
+}
+
+func (a *Authenticator) clientData(t *testing.T, ceremony protocol.CeremonyType, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+	data, err := json.Marshal(protocol.CollectedClientData{
+		Type:      ceremony,
+		Challenge: b64.EncodeToString(challenge),
+		Origin:    a.Origin,
+	})
+	if err != nil {
+		t.Fatalf("\nFailed To Encode Client Data: %s\n", err.Error())
+	}
+	return data
+}
+
+// RP ID Hash, Flags And Sign Count
+func (a *Authenticator) authData(rpID string, flags byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, a.SignCount)
+}
@@ -0,0 +1,211 @@
+/*
+Helpers For Tests That Drive The Whole App In-Process Through app.Test
+Every Setup Gets Its Own Migrated And Seeded SQLite Database
+*/
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/gofiber/fiber/v2"
+
+	"app/api/auth"
+	"app/config"
+	"app/database"
+	"app/mail"
+	"app/models/user"
+	"app/server"
+)
+
+// Test Config - Override Fields Before Calling SetupWith
+func Config(t *testing.T) *config.Config {
+	cfg := config.Default()
+	cfg.App.Debug = false
+	// Keep Access Logs Out Of Test Output
+	cfg.Log.Level = "error"
+	// Tests Log In Far More Often Than Any Policy Allows - Opt In Where Needed
+	cfg.RateLimit.Enabled = false
+	cfg.DB.Driver = config.DriverSQLite
+	cfg.DB.Database = filepath.Join(t.TempDir(), "test.db")
+	return cfg
+}
+
+// Builds The App Against A Fresh Database
+func Setup(t *testing.T) *fiber.App {
+	return SetupWith(t, Config(t))
+}
+
+func SetupWith(t *testing.T, cfg *config.Config) *fiber.App {
+	auth.ClearRevocationCache()
+	user.ClearPermissionCache()
+	app := server.Setup(cfg)
+	// Mail Never Leaves The Test - See DeliverMail
+	mailbox = new(mail.MemorySender)
+	mail.SetSender(mailbox)
+	t.Cleanup(func() {
+		sqlDB, err := database.DB.DB()
+		if err == nil {
+			sqlDB.Close()
+		}
+	})
+	return app
+}
+
+/*
+	Fixtures
+*/
+
+// Looks Up A Seeded Role By Name
+func Role(t *testing.T, name string) user.UserRole {
+	t.Helper()
+	var role user.UserRole
+	err := database.DB.Where("role = ?", name).First(&role).Error
+	if err != nil {
+		t.Fatalf("\nRole %s Doesn't Exist: %s\n", name, err.Error())
+	}
+	return role
+}
+
+// Inserts A User Directly - role Is A Seeded Role Name
+func CreateUser(t *testing.T, username string, password string, role string) user.User {
+	t.Helper()
+	hash, err := user.HashPassword(context.Background(), username, password)
+	if err != nil {
+		t.Fatalf("\nFailed To Hash Password: %s\n", err.Error())
+	}
+
+	u := user.User{
+		Username: username,
+		Password: hash,
+		Email:    username + "@tester.com",
+		RoleID:   Role(t, role).ID,
+	}
+	err = database.DB.Create(&u).Error
+	if err != nil {
+		t.Fatalf("\nFailed To Create User %s: %s\n", username, err.Error())
+	}
+	return u
+}
+
+func SetAccountEnabled(t *testing.T, userID uint, enabled bool) {
+	t.Helper()
+	err := database.DB.Model(&user.User{}).Where("id = ?", userID).Update("account_enabled", enabled).Error
+	if err != nil {
+		t.Fatalf("\nFailed To Update Account: %s\n", err.Error())
+	}
+}
+
+// Logs In Through The API - Returns An Authorization Header Value
+func Login(t *testing.T, app *fiber.App, username string, password string) string {
+	t.Helper()
+	res, body := Request(t, app, http.MethodPost, "/user/login", fiber.Map{"username": username, "password": password}, "")
+	if res.StatusCode != 200 {
+		t.Fatalf("\nLogin Failed For %s: %d %s\n", username, res.StatusCode, body)
+	}
+
+	var login struct {
+		Token string `json:"token"`
+	}
+	Decode(t, body, &login)
+	return "Bearer " + login.Token
+}
+
+/*
+	Mail
+*/
+
+var mailbox *mail.MemorySender
+
+// Sends Everything Due In The Outbox - Returns The Mailbox It Was Delivered To
+func DeliverMail(t *testing.T) *mail.MemorySender {
+	t.Helper()
+	_, err := mail.ProcessOutbox()
+	if err != nil {
+		t.Fatalf("\nFailed To Process Outbox: %s\n", err.Error())
+	}
+	return mailbox
+}
+
+// The Most Recent Message To to, After Delivering The Outbox
+func LastMail(t *testing.T, to string) mail.Message {
+	t.Helper()
+	messages := DeliverMail(t).To(to)
+	if len(messages) == 0 {
+		t.Fatalf("\nNo Mail Sent To %s\n", to)
+	}
+	return messages[len(messages)-1]
+}
+
+// Value Of A Query Parameter In The First Link Of A Message That Has It
+func LinkParam(t *testing.T, msg mail.Message, param string) string {
+	t.Helper()
+	for _, field := range strings.Fields(msg.Text) {
+		link, err := url.Parse(field)
+		if err == nil && link.Query().Get(param) != "" {
+			return link.Query().Get(param)
+		}
+	}
+	t.Fatalf("\nNo Link With %s In Mail: %s\n", param, msg.Text)
+	return ""
+}
+
+/*
+	Requests
+*/
+
+// Sends body As JSON (Skipped If nil) - Returns The Response And Its Body
+func Request(t *testing.T, app *fiber.App, method string, path string, body interface{}, authorization string) (*http.Response, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("\nFailed To Marshal Request: %s\n", err.Error())
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	res, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("\n%s %s Failed: %s\n", method, path, err.Error())
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("\nFailed To Read Response: %s\n", err.Error())
+	}
+	return res, resBody
+}
+
+func Decode(t *testing.T, body []byte, v interface{}) {
+	t.Helper()
+	err := json.Unmarshal(body, v)
+	if err != nil {
+		t.Fatalf("\nFailed To Unmarshal JSON: %s\n%s\n", err.Error(), body)
+	}
+}
+
+func ExpectStatus(t *testing.T, res *http.Response, body []byte, expected int) {
+	t.Helper()
+	if res.StatusCode != expected {
+		t.Fatalf("\nInvalid Status Code: %d Expected: %d\n%s\n", res.StatusCode, expected, body)
+	}
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"app/database"
+)
+
+/*
+Gives Every Statement A Span Under The Span In Its Context - Statements
+Run Outside A Traced Request (Workers, Migrations) Aren't Traced
+*/
+func InstrumentDB(db *gorm.DB) error {
+	return db.Use(gormPlugin{})
+}
+
+const spanKey = "tracing:span"
+
+type gormPlugin struct{}
+
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	return database.Hook(db, "tracing", startSpan, endSpan)
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return
+		}
+		_, span := Tracer().Start(ctx, "gorm."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			semconv.DBSystemKey.String(db.Dialector.Name()),
+			semconv.DBOperation(operation),
+		))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endSpan(string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(spanKey)
+		if !ok {
+			return
+		}
+		span, ok := value.(trace.Span)
+		if !ok {
+			return
+		}
+
+		// Placeholders Only - Values Never Reach The Span
+		span.SetAttributes(
+			semconv.DBStatement(db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+		)
+		if db.Statement.Table != "" {
+			span.SetAttributes(semconv.DBSQLTable(db.Statement.Table))
+		}
+		err := db.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		End(span, err)
+	}
+}
@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"app/logging"
+	"app/util"
+)
+
+// propagation.TextMapCarrier Over The Request Headers
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key string, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	headers := h.c.GetReqHeaders()
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+/*
+Opens A Server Span For Each Request - A Child Of The Caller's When It Sent
+A traceparent - And Hands It To Handlers Through c.UserContext. Log Lines
+From Here On Carry The trace_id. Goes After logging.RequestID
+*/
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		method := utils.CopyString(c.Method())
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c})
+		ctx, span := Tracer().Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.URLPath(utils.CopyString(c.Path())),
+			semconv.ClientAddress(c.IP()),
+			semconv.UserAgentOriginal(c.Get(fiber.HeaderUserAgent)),
+			attribute.String("request_id", logging.RequestIDFrom(c)),
+		))
+		defer span.End()
+
+		c.SetUserContext(ctx)
+		if sc := span.SpanContext(); sc.HasTraceID() {
+			logging.With(c, "trace_id", sc.TraceID().String())
+		}
+
+		err := c.Next()
+
+		// Unmatched Requests Keep The Bare Method So Scanned Paths Don't Name Spans
+		if route, matched := util.MatchedRoute(c); matched {
+			span.SetName(method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		status := c.Response().StatusCode()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, utils.StatusMessage(status))
+		}
+		return err
+	}
+}
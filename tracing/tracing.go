@@ -0,0 +1,122 @@
+/*
+OpenTelemetry Tracing - A Span For Every Request, The Auth Middleware,
+Password Hashing And Every Statement Run Through database.For. Callers'
+W3C traceparent Headers Are Honoured So Our Spans Join Their Traces
+*/
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"app/config"
+)
+
+// Instrumentation Scope Every Span Is Recorded Under
+const scope = "app"
+
+var provider atomic.Pointer[sdktrace.TracerProvider]
+
+// Where The memory Exporter Keeps Spans - See Spans
+var memory = tracetest.NewInMemoryExporter()
+
+/*
+Builds The Tracer Provider For cfg And Makes It Global - Any Previous One
+Is Flushed And Shut Down. With Tracing Off Spans Are Dropped But
+traceparent Is Still Read So Logs Carry The Caller's Trace ID
+*/
+func Configure(cfg *config.Config) error {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var tp *sdktrace.TracerProvider
+	if cfg.Tracing.Enabled {
+		exporter, err := newExporter(cfg.Tracing)
+		if err != nil {
+			return err
+		}
+		options := []sdktrace.TracerProviderOption{
+			sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.Tracing.ServiceName))),
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+		}
+		// Spans Are Readable As Soon As They End Rather Than When A Batch Fills
+		if cfg.Tracing.Exporter == config.TracingMemory {
+			options = append(options, sdktrace.WithSyncer(exporter))
+		} else {
+			options = append(options, sdktrace.WithBatcher(exporter))
+		}
+		tp = sdktrace.NewTracerProvider(options...)
+	}
+
+	if tp != nil {
+		otel.SetTracerProvider(tp)
+	} else {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+	}
+	previous := provider.Swap(tp)
+	if previous != nil {
+		return previous.Shutdown(context.Background())
+	}
+	return nil
+}
+
+func newExporter(cfg config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case config.TracingOTLP:
+		return otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	case config.TracingStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case config.TracingMemory:
+		return memory, nil
+	}
+	return nil, fmt.Errorf("Unknown Tracing Exporter: %q", cfg.Exporter)
+}
+
+// Sends Any Spans Still Buffered - Call Before Exiting
+func Shutdown(ctx context.Context) error {
+	tp := provider.Swap(nil)
+	if tp == nil {
+		return nil
+	}
+	return tp.Shutdown(ctx)
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(scope)
+}
+
+// Starts A Span Under Whatever Span ctx Carries
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Ends span, Marking It Failed When err Isn't Nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Spans Collected By The memory Exporter, Oldest First
+func Spans() tracetest.SpanStubs {
+	return memory.GetSpans()
+}
+
+func ResetSpans() {
+	memory.Reset()
+}
@@ -0,0 +1,174 @@
+package tracing_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"app/api/auth"
+	"app/config"
+	"app/testutil"
+	"app/tracing"
+)
+
+// Caller's Trace - Version, Trace ID, Parent Span ID, Sampled
+const (
+	callerTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	callerSpanID  = "00f067aa0ba902b7"
+	traceparent   = "00-" + callerTraceID + "-" + callerSpanID + "-01"
+)
+
+func tracedApp(t *testing.T) *fiber.App {
+	cfg := testutil.Config(t)
+	cfg.Tracing.Enabled = true
+	cfg.Tracing.Exporter = config.TracingMemory
+	app := testutil.SetupWith(t, cfg)
+	// Later Tests Get Tracing Off Again
+	t.Cleanup(func() {
+		tracing.Configure(config.Default())
+	})
+	return app
+}
+
+func find(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	t.Fatalf("\nMissing Span %q In: %v\n", name, names)
+	return tracetest.SpanStub{}
+}
+
+func attr(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestLoginTrace(t *testing.T) {
+	app := tracedApp(t)
+	testutil.CreateUser(t, "jane", "123", "default")
+	tracing.ResetSpans()
+
+	payload, _ := json.Marshal(fiber.Map{"username": "jane", "password": "123"})
+	req := httptest.NewRequest(http.MethodPost, "/user/login", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", traceparent)
+	res, err := app.Test(req, -1)
+	if err != nil || res.StatusCode != 200 {
+		t.Fatalf("\nLogin Failed: %v %v\n", res, err)
+	}
+
+	spans := tracing.Spans()
+	server := find(t, spans, "POST /user/login")
+	if server.SpanContext.TraceID().String() != callerTraceID || server.Parent.SpanID().String() != callerSpanID {
+		t.Fatalf("\nServer Span Isn't Part Of The Caller's Trace: %s %s\n", server.SpanContext.TraceID(), server.Parent.SpanID())
+	}
+	if attr(server, semconv.HTTPRouteKey).AsString() != "/user/login" || attr(server, semconv.HTTPResponseStatusCodeKey).AsInt64() != 200 {
+		t.Fatalf("\nUnexpected Server Span Attributes: %v\n", server.Attributes)
+	}
+
+	// bcrypt And The User Lookup Hang Off The Request
+	for _, child := range []tracetest.SpanStub{find(t, spans, "bcrypt.compare"), find(t, spans, "gorm.query")} {
+		if child.Parent.SpanID() != server.SpanContext.SpanID() {
+			t.Fatalf("\n%s Isn't A Child Of The Request\n", child.Name)
+		}
+	}
+	var tables []string
+	for _, span := range spans {
+		if span.Name == "gorm.query" {
+			tables = append(tables, attr(span, semconv.DBSQLTableKey).AsString())
+		}
+	}
+	if !contains(tables, "users") || !contains(tables, "user_roles") {
+		t.Fatalf("\nExpected Queries On users And user_roles, Got: %v\n", tables)
+	}
+}
+
+func TestMiddlewareSpans(t *testing.T) {
+	app := tracedApp(t)
+	testutil.CreateUser(t, "jane", "123", "default")
+	authorization := testutil.Login(t, app, "jane", "123")
+	// Revocation Checks Go To The Database Rather Than The Cache
+	auth.ClearRevocationCache()
+	tracing.ResetSpans()
+
+	res, body := testutil.Request(t, app, http.MethodGet, "/user/", nil, authorization)
+	testutil.ExpectStatus(t, res, body, 200)
+
+	spans := tracing.Spans()
+	server := find(t, spans, "GET /user/")
+	validate := find(t, spans, "auth.ValidateJWT")
+	enabled := find(t, spans, "user.VerifyAccountEnabled")
+	if validate.Parent.SpanID() != server.SpanContext.SpanID() || enabled.Parent.SpanID() != server.SpanContext.SpanID() {
+		t.Fatalf("\nMiddleware Spans Aren't Children Of The Request\n")
+	}
+	if attr(validate, "token.result").AsString() != "valid" {
+		t.Fatalf("\nUnexpected Token Result: %v\n", validate.Attributes)
+	}
+	// Ended Before The Handler Ran
+	if validate.EndTime.After(enabled.StartTime) {
+		t.Fatalf("\nValidateJWT Span Covers The Rest Of The Request\n")
+	}
+	lookup, revocation := false, false
+	for _, span := range spans {
+		if span.Name == "gorm.query" && span.Parent.SpanID() == enabled.SpanContext.SpanID() {
+			lookup = true
+		}
+		if strings.HasPrefix(span.Name, "gorm.") && span.Parent.SpanID() == validate.SpanContext.SpanID() {
+			revocation = true
+		}
+	}
+	if !lookup {
+		t.Fatalf("\nAccount Lookup Isn't Under VerifyAccountEnabled\n")
+	}
+	if !revocation {
+		t.Fatalf("\nRevocation Checks Aren't Under ValidateJWT\n")
+	}
+
+	// Refused Tokens Are Noted But Aren't Failures
+	tracing.ResetSpans()
+	res, body = testutil.Request(t, app, http.MethodGet, "/user/", nil, "Bearer nonsense")
+	testutil.ExpectStatus(t, res, body, 401)
+	validate = find(t, tracing.Spans(), "auth.ValidateJWT")
+	if attr(validate, "token.result").AsString() != "malformed" || validate.Status.Code == codes.Error {
+		t.Fatalf("\nUnexpected Span For A Refused Token: %v %v\n", validate.Attributes, validate.Status)
+	}
+}
+
+func TestTracingDisabled(t *testing.T) {
+	app := testutil.Setup(t)
+	tracing.ResetSpans()
+	testutil.CreateUser(t, "jane", "123", "default")
+	testutil.Login(t, app, "jane", "123")
+	if len(tracing.Spans()) != 0 {
+		t.Fatalf("\nSpans Recorded With Tracing Off: %d\n", len(tracing.Spans()))
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
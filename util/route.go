@@ -0,0 +1,12 @@
+package util
+
+import "github.com/gofiber/fiber/v2"
+
+/*
+The Route Pattern The Request Matched (/user/:id, Not /user/7) - For Middleware
+Mounted At / Once c.Next Returns. Still Being At / Then Means Nothing Matched
+*/
+func MatchedRoute(c *fiber.Ctx) (string, bool) {
+	route := c.Route().Path
+	return route, route != "/" || c.Path() == "/"
+}
@@ -1,8 +1,7 @@
 package util
 
 import (
-	"app/config"
-	"log"
+	"app/logging"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -13,9 +12,7 @@ func Validate(r interface{}) error {
 	validate_err := validate.Struct(r)
 
 	if validate_err != nil {
-		if config.DEBUG {
-			log.Println("Validation Error: ", validate_err)
-		}
+		logging.Get().Debug("Validation Error", "error", validate_err)
 		return validate_err
 	}
 	return nil